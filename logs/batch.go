@@ -0,0 +1,273 @@
+package logs
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var BatchQueueFull = errors.New("batch queue is full, record dropped")
+
+// DeliveryStats count records of adapter which send records to remote in batches
+type DeliveryStats struct {
+	Adapter string
+	// Records sent successfully
+	Sent    uint64
+	Batches uint64
+	Retries uint64
+	// Records failed after all retries and not spooled
+	Failed uint64
+	// Records dropped as queue is full
+	Dropped uint64
+	// Records saved to spool after all retries failed
+	Spooled   uint64
+	LastError string
+}
+
+// implemented by adapters which deliver records to remote
+type deliveryWriter interface {
+	deliveryStats() DeliveryStats
+}
+
+// DeliveryStats return stats of every adapter delivering records to remote, like http
+func (logger *Logger) DeliveryStats() (stats []DeliveryStats) {
+	for _, writer := range logger.recorder {
+		if dw, ok := unwrapWriter(writer).(deliveryWriter); ok {
+			stats = append(stats, dw.deliveryStats())
+		}
+	}
+	return
+}
+
+// deliveryCounter is the atomic version of DeliveryStats
+type deliveryCounter struct {
+	sent    uint64
+	batches uint64
+	retries uint64
+	failed  uint64
+	dropped uint64
+	spooled uint64
+
+	lastError atomic.Value
+}
+
+func (c *deliveryCounter) setError(err error) {
+	c.lastError.Store(err.Error())
+}
+
+func (c *deliveryCounter) stats(adapter string) DeliveryStats {
+	lastError, _ := c.lastError.Load().(string)
+	return DeliveryStats{
+		Adapter:   adapter,
+		Sent:      atomic.LoadUint64(&c.sent),
+		Batches:   atomic.LoadUint64(&c.batches),
+		Retries:   atomic.LoadUint64(&c.retries),
+		Failed:    atomic.LoadUint64(&c.failed),
+		Dropped:   atomic.LoadUint64(&c.dropped),
+		Spooled:   atomic.LoadUint64(&c.spooled),
+		LastError: lastError,
+	}
+}
+
+// batchOptions are the batch and retry part of helper, shared by adapters sending batches
+type batchOptions struct {
+	// A batch is sent when any of them reached
+	BatchSize     int      `json:"batchsize"`
+	BatchBytes    ByteSize `json:"batchbytes"`
+	FlushInterval Duration `json:"flushinterval"`
+	// Records wait to be batched, more records are dropped
+	QueueSize int `json:"queuesize"`
+
+	// Retry temporary failure, delay double after each retry until MaxBackoff
+	Retries    int      `json:"retries"`
+	Backoff    Duration `json:"backoff"`
+	MaxBackoff Duration `json:"maxbackoff"`
+}
+
+func defaultBatchOptions() batchOptions {
+	return batchOptions{
+		BatchSize:     100,
+		BatchBytes:    1 << 20,
+		FlushInterval: Duration(time.Second),
+		QueueSize:     10000,
+		Retries:       3,
+		Backoff:       Duration(500 * time.Millisecond),
+		MaxBackoff:    Duration(10 * time.Second),
+	}
+}
+
+func (o *batchOptions) validate(adapter string) error {
+	switch {
+	case o.BatchSize <= 0:
+		return newConfigError(adapter, "batchsize", "must be positive")
+	case o.BatchBytes <= 0:
+		return newConfigError(adapter, "batchbytes", "must be positive")
+	case o.FlushInterval <= 0:
+		return newConfigError(adapter, "flushinterval", "must be positive")
+	case o.QueueSize <= 0:
+		return newConfigError(adapter, "queuesize", "must be positive")
+	case o.Retries < 0:
+		return newConfigError(adapter, "retries", "must not be negative")
+	case o.Backoff <= 0:
+		return newConfigError(adapter, "backoff", "must be positive")
+	case o.MaxBackoff < o.Backoff:
+		return newConfigError(adapter, "maxbackoff", "must not be less than backoff")
+	}
+	return nil
+}
+
+func (o *batchOptions) newBatcher(send func(batch []batchRecord)) *batcher {
+	return newBatcher(o.BatchSize, int(o.BatchBytes), time.Duration(o.FlushInterval), o.QueueSize, send)
+}
+
+// retry call fn until it success, the error is not retryable or retries used up
+func (o *batchOptions) retry(counter *deliveryCounter, fn func() (retryable bool, err error)) error {
+	delay := time.Duration(o.Backoff)
+	for attempt := 0; ; attempt++ {
+		retryable, err := fn()
+		if err == nil || !retryable || attempt >= o.Retries {
+			return err
+		}
+
+		atomic.AddUint64(&counter.retries, 1)
+		wait := delay
+		if statusErr, ok := err.(*HTTPStatusError); ok && statusErr.RetryAfter > wait {
+			// obey the server, but not longer than MaxBackoff
+			if wait = statusErr.RetryAfter; wait > time.Duration(o.MaxBackoff) {
+				wait = time.Duration(o.MaxBackoff)
+			}
+		}
+		time.Sleep(wait)
+		if delay *= 2; delay > time.Duration(o.MaxBackoff) {
+			delay = time.Duration(o.MaxBackoff)
+		}
+	}
+}
+
+// batchRecord is a record with its encoded data
+type batchRecord struct {
+	message logMessage
+	data    []byte
+}
+
+// batcher collect records in a goroutine, call send when there are maxCount
+// records, maxBytes data or the first record has waited maxDelay
+type batcher struct {
+	maxCount int
+	maxBytes int
+	maxDelay time.Duration
+	send     func(batch []batchRecord)
+
+	lock    sync.RWMutex
+	closed  bool
+	queue   chan batchRecord
+	flushCh chan chan struct{}
+	done    chan struct{}
+}
+
+func newBatcher(maxCount int, maxBytes int, maxDelay time.Duration, queueSize int,
+	send func(batch []batchRecord)) *batcher {
+	b := &batcher{
+		maxCount: maxCount,
+		maxBytes: maxBytes,
+		maxDelay: maxDelay,
+		send:     send,
+		queue:    make(chan batchRecord, queueSize),
+		flushCh:  make(chan chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go b.run()
+	return b
+}
+
+// add queue record without blocking, return BatchQueueFull if queue is full
+func (b *batcher) add(record batchRecord) error {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	if b.closed {
+		return BatchQueueFull
+	}
+
+	select {
+	case b.queue <- record:
+		return nil
+	default:
+		return BatchQueueFull
+	}
+}
+
+// flush send queued records and wait them finished
+func (b *batcher) flush() {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	if b.closed {
+		return
+	}
+
+	ack := make(chan struct{})
+	b.flushCh <- ack
+	<-ack
+}
+
+// close send all queued records and stop
+func (b *batcher) close() {
+	b.lock.Lock()
+	if !b.closed {
+		b.closed = true
+		close(b.queue)
+	}
+	b.lock.Unlock()
+	<-b.done
+}
+
+func (b *batcher) run() {
+	var (
+		batch    []batchRecord
+		size     int
+		deadline <-chan time.Time
+	)
+
+	flush := func() {
+		if len(batch) > 0 {
+			b.send(batch)
+		}
+		batch, size, deadline = nil, 0, nil
+	}
+	push := func(record batchRecord) {
+		if len(batch) > 0 && size+len(record.data) > b.maxBytes {
+			flush()
+		}
+		if len(batch) == 0 {
+			deadline = time.After(b.maxDelay)
+		}
+		batch = append(batch, record)
+		size += len(record.data)
+		if len(batch) >= b.maxCount || size >= b.maxBytes {
+			flush()
+		}
+	}
+
+	for {
+		select {
+		case record, ok := <-b.queue:
+			if !ok {
+				flush()
+				close(b.done)
+				return
+			}
+			push(record)
+
+		case <-deadline:
+			flush()
+
+		case ack := <-b.flushCh:
+			// take all queued records before flush
+			for pending := len(b.queue); pending > 0; pending-- {
+				push(<-b.queue)
+			}
+			flush()
+			close(ack)
+		}
+	}
+}
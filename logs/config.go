@@ -0,0 +1,205 @@
+package logs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ConfigError report which option of adapter helper is invalid
+type ConfigError struct {
+	Adapter string
+	// Field is the json key in helper, empty means the helper itself
+	Field  string
+	Reason string
+}
+
+func (e *ConfigError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("%s adapter: invalid helper: %s", e.Adapter, e.Reason)
+	}
+	return fmt.Sprintf("%s adapter: invalid option %q: %s", e.Adapter, e.Field, e.Reason)
+}
+
+func newConfigError(adapter string, field string, format string, args ...interface{}) *ConfigError {
+	return &ConfigError{Adapter: adapter, Field: field, Reason: fmt.Sprintf(format, args...)}
+}
+
+// decodeHelper decode helper json into v, unknown key is an error.
+// Every key is decoded alone so the error can name the key
+func decodeHelper(adapter string, helper string, v interface{}) error {
+	var options map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(helper), &options); err != nil {
+		return newConfigError(adapter, "", "%s", err)
+	}
+
+	keys := make([]string, 0, len(options))
+	for key := range options {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		quoted, _ := json.Marshal(key)
+		one := append(append(append([]byte{'{'}, quoted...), ':'), options[key]...)
+		one = append(one, '}')
+
+		dec := json.NewDecoder(bytes.NewReader(one))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(v); err != nil {
+			return newConfigError(adapter, key, "%s", helperErrorReason(err))
+		}
+	}
+	return nil
+}
+
+func helperErrorReason(err error) string {
+	switch e := err.(type) {
+	case *json.UnmarshalTypeError:
+		return fmt.Sprintf("can not use %s as %s", e.Value, e.Type)
+	}
+	// encoding/json have no type for unknown field error
+	if strings.HasPrefix(err.Error(), "json: unknown field ") {
+		return "unknown option"
+	}
+	return strings.TrimPrefix(err.Error(), "json: ")
+}
+
+// ByteSize is a count of bytes, in json can be a number or string like "100MB", "1GiB"
+type ByteSize int64
+
+var byteSizeUnits = map[string]int64{
+	"":    1,
+	"b":   1,
+	"k":   1 << 10,
+	"kb":  1000,
+	"kib": 1 << 10,
+	"m":   1 << 20,
+	"mb":  1000 * 1000,
+	"mib": 1 << 20,
+	"g":   1 << 30,
+	"gb":  1000 * 1000 * 1000,
+	"gib": 1 << 30,
+	"t":   1 << 40,
+	"tb":  1000 * 1000 * 1000 * 1000,
+	"tib": 1 << 40,
+}
+
+// ParseByteSize parse size like "512", "100MB", "1.5GiB".
+// KB, MB... are power of 1000, K, KiB, M, MiB... are power of 1024
+func ParseByteSize(s string) (ByteSize, error) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i == -1 {
+		i = len(s)
+	}
+	num, unit := s[:i], strings.ToLower(strings.TrimSpace(s[i:]))
+
+	n, err := strconv.ParseFloat(num, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	mul, ok := byteSizeUnits[unit]
+	if !ok {
+		return 0, fmt.Errorf("invalid size unit %q", s[i:])
+	}
+	return ByteSize(n * float64(mul)), nil
+}
+
+func (b *ByteSize) UnmarshalJSON(data []byte) (err error) {
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err = json.Unmarshal(data, &s); err != nil {
+			return
+		}
+		*b, err = ParseByteSize(s)
+		return
+	}
+
+	var n int64
+	if err = json.Unmarshal(data, &n); err != nil {
+		return fmt.Errorf("invalid size %s", bytes.TrimSpace(data))
+	}
+	if n < 0 {
+		return fmt.Errorf("invalid size %d", n)
+	}
+	*b = ByteSize(n)
+	return
+}
+
+// Duration is time.Duration, in json can be number of seconds or
+// string like "1h30m", and also support "d" for day and "w" for week
+type Duration time.Duration
+
+// ParseDuration work as time.ParseDuration with additional unit "d" and "w"
+func ParseDuration(s string) (time.Duration, error) {
+	orig := s
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, fmt.Errorf("invalid duration %q", orig)
+	}
+
+	var total time.Duration
+	for s != "" {
+		i := strings.IndexFunc(s, func(r rune) bool {
+			return (r < '0' || r > '9') && r != '.'
+		})
+		if i <= 0 {
+			return 0, fmt.Errorf("invalid duration %q", orig)
+		}
+		j := strings.IndexFunc(s[i:], func(r rune) bool {
+			return (r >= '0' && r <= '9') || r == '.'
+		})
+		if j == -1 {
+			j = len(s) - i
+		}
+		num, unit := s[:i], s[i:i+j]
+		s = s[i+j:]
+
+		var d time.Duration
+		switch unit {
+		case "d", "w":
+			n, err := strconv.ParseFloat(num, 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration %q", orig)
+			}
+			d = time.Duration(n * float64(24*time.Hour))
+			if unit == "w" {
+				d *= 7
+			}
+		default:
+			var err error
+			if d, err = time.ParseDuration(num + unit); err != nil {
+				return 0, fmt.Errorf("invalid duration %q", orig)
+			}
+		}
+		total += d
+	}
+	return total, nil
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) (err error) {
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err = json.Unmarshal(data, &s); err != nil {
+			return
+		}
+		var td time.Duration
+		td, err = ParseDuration(s)
+		*d = Duration(td)
+		return
+	}
+
+	var n float64
+	if err = json.Unmarshal(data, &n); err != nil || n < 0 {
+		return fmt.Errorf("invalid duration %s", bytes.TrimSpace(data))
+	}
+	*d = Duration(n * float64(time.Second))
+	return
+}
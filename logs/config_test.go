@@ -0,0 +1,92 @@
+package logs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseByteSize(t *testing.T) {
+	cases := map[string]ByteSize{
+		"512":    512,
+		"10B":    10,
+		"1k":     1024,
+		"1KB":    1000,
+		"100MB":  100 * 1000 * 1000,
+		"1GiB":   1 << 30,
+		"1.5MiB": 3 << 19,
+		" 2 M ":  2 << 20,
+	}
+	for s, expected := range cases {
+		size, err := ParseByteSize(s)
+		if err != nil {
+			t.Error(s, err)
+		} else if size != expected {
+			t.Error(s, size, "not", expected)
+		}
+	}
+
+	for _, s := range []string{"", "MB", "-1MB", "10XB", "1..2KB"} {
+		if _, err := ParseByteSize(s); err == nil {
+			t.Error(s, "should be invalid")
+		}
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	cases := map[string]time.Duration{
+		"7d":      7 * 24 * time.Hour,
+		"1w":      7 * 24 * time.Hour,
+		"1d12h":   36 * time.Hour,
+		"1.5d":    36 * time.Hour,
+		"90m":     90 * time.Minute,
+		"1h30m5s": time.Hour + 30*time.Minute + 5*time.Second,
+	}
+	for s, expected := range cases {
+		d, err := ParseDuration(s)
+		if err != nil {
+			t.Error(s, err)
+		} else if d != expected {
+			t.Error(s, d, "not", expected)
+		}
+	}
+
+	for _, s := range []string{"", "7", "d", "7x", "1d-2h"} {
+		if _, err := ParseDuration(s); err == nil {
+			t.Error(s, "should be invalid")
+		}
+	}
+}
+
+func TestDecodeHelper(t *testing.T) {
+	var v struct {
+		Size ByteSize `json:"size"`
+		Age  Duration `json:"age"`
+		Num  int      `json:"num"`
+	}
+
+	if err := decodeHelper("test", `{"size":"1KiB", "age":"2d", "num":3}`, &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.Size != 1024 || time.Duration(v.Age) != 48*time.Hour || v.Num != 3 {
+		t.Error("decode wrong:", v)
+	}
+
+	cases := map[string]string{
+		`{"sise":"1KiB"}`:   "sise",
+		`{"size":"1XB"}`:    "size",
+		`{"age":"forever"}`: "age",
+		`{"num":"3"}`:       "num",
+		`{"num":3`:          "",
+	}
+	for helper, field := range cases {
+		err := decodeHelper("test", helper, &v)
+		cErr, ok := err.(*ConfigError)
+		if !ok {
+			t.Error(helper, "not get ConfigError but", err)
+			continue
+		}
+		if cErr.Adapter != "test" || cErr.Field != field {
+			t.Error(helper, "get error on", cErr.Field, "not", field)
+		}
+	}
+}
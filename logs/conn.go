@@ -0,0 +1,102 @@
+package logs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"io/ioutil"
+	"net"
+	"time"
+)
+
+// tlsOptions are the tls part of helper, shared by network adapters
+type tlsOptions struct {
+	TLSCA         string `json:"tlsca"`
+	TLSServerName string `json:"tlsservername"`
+	TLSSkipVerify bool   `json:"tlsskipverify"`
+}
+
+func (o *tlsOptions) tlsConfig(adapter string) (*tls.Config, error) {
+	config := &tls.Config{
+		ServerName:         o.TLSServerName,
+		InsecureSkipVerify: o.TLSSkipVerify,
+	}
+	if o.TLSCA != "" {
+		pem, err := ioutil.ReadFile(o.TLSCA)
+		if err != nil {
+			return nil, newConfigError(adapter, "tlsca", "%s", err)
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, newConfigError(adapter, "tlsca", "no certificate found in %s", o.TLSCA)
+		}
+	}
+	return config, nil
+}
+
+func dialConn(network string, address string, timeout time.Duration, config *tls.Config) (net.Conn, error) {
+	if config != nil {
+		dialer := &net.Dialer{Timeout: timeout}
+		return tls.DialWithDialer(dialer, network, address, config)
+	}
+	return net.DialTimeout(network, address, timeout)
+}
+
+func isStreamNetwork(network string) bool {
+	switch network {
+	case "tcp", "tcp4", "tcp6", "unix", "tls":
+		return true
+	}
+	return false
+}
+
+// watchPeerClose return a chan closed when peer close conn, as a write to
+// closed tcp conn may success and the data will be lost.
+// Only for protocol which peer never send anything
+func watchPeerClose(conn net.Conn) chan struct{} {
+	closed := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(ioutil.Discard, conn)
+		close(closed)
+	}()
+	return closed
+}
+
+func isClosed(ch chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
+// backoff is exponential delay between reconnect
+type backoff struct {
+	initial time.Duration
+	max     time.Duration
+	cur     time.Duration
+	next    time.Time
+}
+
+// ready report whether it is time to try again
+func (b *backoff) ready() bool {
+	return !time.Now().Before(b.next)
+}
+
+func (b *backoff) fail() {
+	if b.cur == 0 {
+		b.cur = b.initial
+	} else {
+		b.cur *= 2
+	}
+	if b.cur > b.max {
+		b.cur = b.max
+	}
+	b.next = time.Now().Add(b.cur)
+}
+
+func (b *backoff) reset() {
+	b.cur = 0
+	b.next = time.Time{}
+}
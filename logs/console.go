@@ -1,15 +1,48 @@
 package logs
 
 import (
-	"encoding/json"
-	"fmt"
 	"os"
+	"strconv"
+	"strings"
 )
 
+const (
+	ConsoleStdout = "stdout"
+	ConsoleStderr = "stderr"
+	// records below split level to stdout, others to stderr
+	ConsoleSplit = "split"
+)
+
+const (
+	ConsoleColorAuto   = "auto"
+	ConsoleColorAlways = "always"
+	ConsoleColorNever  = "never"
+)
+
+const (
+	ansiReset = "\x1b[0m"
+	ansiDim   = "\x1b[2m"
+	ansiCyan  = "\x1b[36m"
+)
+
+// ansi color of each level tag
+var levelColor = []string{"\x1b[90m", "\x1b[34m", "\x1b[32m", "\x1b[33m", "\x1b[1;31m"}
+
 type consoleWriter struct {
 	consoleWriter *os.File
 	level         int
-	WriterName    string `json:"writer"`
+	// stdout, stderr or split
+	WriterName string `json:"writer"`
+	// Records below it are written to stdout in split mode
+	SplitLevel string `json:"splitlevel"`
+	// auto, always or never, auto means color when writing to a terminal and NO_COLOR is not set
+	Color string `json:"color"`
+	color bool
+
+	// writer of records below split level in split mode, nil if not split
+	lowWriter  *os.File
+	lowColor   bool
+	splitLevel int
 }
 
 func (w *consoleWriter) WriteMsg(message logMessage) (err error) {
@@ -17,20 +50,61 @@ func (w *consoleWriter) WriteMsg(message logMessage) (err error) {
 		return nil
 	}
 
+	out, color := w.consoleWriter, w.color
+	if w.lowWriter != nil && message.level < w.splitLevel {
+		out, color = w.lowWriter, w.lowColor
+	}
+
 	var msg string
-	if w.level == LevelTrace {
-		msg = fmt.Sprintf("%s %s [%s] [%s:%d] - %s\n", message.timeString, levelString[message.level],
-			message.trace.funcName, message.trace.file, message.trace.line, message.message)
+	if color {
+		msg = colorTextMessage(w.level, message)
 	} else {
-		msg = fmt.Sprintf("%s %s - %s\n", message.timeString, levelString[message.level], message.message)
+		msg = textMessage(w.level, message)
 	}
-
-	_, err = w.consoleWriter.Write([]byte(msg))
+	_, err = out.Write([]byte(msg))
 	return
 }
 
+// colorTextMessage is textMessage with the level tag colored, time and caller dimmed
+// and field keys highlighted
+func colorTextMessage(writerLevel int, message logMessage) string {
+	var b strings.Builder
+	b.WriteString(ansiDim + message.timeString + ansiReset + " ")
+	b.WriteString(levelColor[message.level] + levelString[message.level] + ansiReset + " ")
+	if writerLevel == LevelTrace {
+		b.WriteString(ansiDim + "[" + message.trace.funcName + "] [" + message.trace.file + ":" +
+			strconv.Itoa(message.trace.line) + "]" + ansiReset + " ")
+	}
+	b.WriteString("- " + message.message)
+	for _, k := range sortedFieldKeys(message.fields) {
+		b.WriteString(" " + ansiCyan + k + ansiReset + "=" + fieldValueString(message.fields[k]))
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// useColor decide whether to color output to f in mode
+func useColor(mode string, f *os.File) bool {
+	switch mode {
+	case ConsoleColorAlways:
+		return true
+	case ConsoleColorNever:
+		return false
+	}
+
+	// https://no-color.org
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
 func (w *consoleWriter) Flush() {
 	_ = w.consoleWriter.Sync()
+	if w.lowWriter != nil {
+		_ = w.lowWriter.Sync()
+	}
 }
 
 func (w *consoleWriter) Destroy() {
@@ -41,17 +115,40 @@ func newConsoleAdapter(level string, helper string) (writer *consoleWriter, err
 
 	w := getConsoleWriter()
 
-	if err = json.Unmarshal([]byte(helper), &w); err != nil {
+	if err = decodeHelper(AdapterConsole, helper, w); err != nil {
+		return
+	}
+
+	if w.level = getLevelInt(level); w.level == -1 {
+		err = NoSupportLevel
 		return
 	}
 
 	switch w.WriterName {
-	case "stdout":
+	case ConsoleStdout:
 		w.consoleWriter = os.Stdout
+	case ConsoleStderr:
+		w.consoleWriter = os.Stderr
+	case ConsoleSplit:
+		w.consoleWriter, w.lowWriter = os.Stderr, os.Stdout
+		if w.splitLevel = getLevelInt(w.SplitLevel); w.splitLevel == -1 {
+			err = newConfigError(AdapterConsole, "splitlevel", "unknown level %q", w.SplitLevel)
+			return
+		}
+	default:
+		err = newConfigError(AdapterConsole, "writer", "unknown writer %q", w.WriterName)
+		return
 	}
 
-	if w.level = getLevelInt(level); w.level == -1 {
-		err = NoSupportLevel
+	switch w.Color {
+	case ConsoleColorAuto, ConsoleColorAlways, ConsoleColorNever:
+	default:
+		err = newConfigError(AdapterConsole, "color", "unknown color mode %q", w.Color)
+		return
+	}
+	w.color = useColor(w.Color, w.consoleWriter)
+	if w.lowWriter != nil {
+		w.lowColor = useColor(w.Color, w.lowWriter)
 	}
 
 	writer = w
@@ -61,6 +158,9 @@ func newConsoleAdapter(level string, helper string) (writer *consoleWriter, err
 func getConsoleWriter() *consoleWriter {
 	return &consoleWriter{
 		consoleWriter: os.Stderr,
+		WriterName:    ConsoleStderr,
+		SplitLevel:    LevelWarningStr,
+		Color:         ConsoleColorAuto,
 		level:         LevelInfo,
 	}
 }
@@ -1,6 +1,9 @@
 package logs
 
 import (
+	"io/ioutil"
+	"os"
+	"strings"
 	"testing"
 )
 
@@ -31,3 +34,99 @@ func TestAsyncSingleConsoleWriter(t *testing.T) {
 	log.Close()
 
 }
+
+func TestConsoleColor(t *testing.T) {
+	f, err := ioutil.TempFile("", "console")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	// a regular file is not a terminal
+	if useColor(ConsoleColorAuto, f) {
+		t.Error("auto color on non terminal")
+	}
+	if !useColor(ConsoleColorAlways, f) || useColor(ConsoleColorNever, f) {
+		t.Error("color mode not obeyed")
+	}
+
+	w, err := newConsoleAdapter(LevelTraceStr, `{"color":"always"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.consoleWriter = f
+	_ = w.WriteMsg(logMessage{timeString: "now", level: LevelError, message: "denied",
+		trace: traceStruct{file: "a.go", line: 7, funcName: "main"}, fields: Fields{"user": "box"}})
+
+	data, _ := ioutil.ReadFile(f.Name())
+	expected := "\x1b[2mnow\x1b[0m \x1b[1;31m  [error]\x1b[0m \x1b[2m[main] [a.go:7]\x1b[0m - denied \x1b[36muser\x1b[0m=box\n"
+	if string(data) != expected {
+		t.Errorf("wrong colored line: %q", data)
+	}
+}
+
+func TestConsoleNoColorEnv(t *testing.T) {
+	// null device is a char device, colored as a terminal without NO_COLOR
+	f, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	term, noColor := os.Getenv("TERM"), os.Getenv("NO_COLOR")
+	defer os.Setenv("TERM", term)
+	defer os.Setenv("NO_COLOR", noColor)
+	_ = os.Setenv("TERM", "xterm")
+	_ = os.Unsetenv("NO_COLOR")
+	if !useColor(ConsoleColorAuto, f) {
+		t.Skip(os.DevNull, "is not a char device")
+	}
+
+	_ = os.Setenv("NO_COLOR", "1")
+	if useColor(ConsoleColorAuto, f) {
+		t.Error("NO_COLOR not obeyed")
+	}
+}
+
+func TestConsoleSplit(t *testing.T) {
+	out, _ := ioutil.TempFile("", "stdout")
+	errOut, _ := ioutil.TempFile("", "stderr")
+	defer os.Remove(out.Name())
+	defer os.Remove(errOut.Name())
+
+	log := NewLogger()
+	if err := log.AddAdapter(AdapterConsole, LevelDebugStr, `{"writer":"split", "splitlevel":"error", "color":"never"}`); err != nil {
+		t.Fatal(err)
+	}
+	w := log.recorder[0].(*consoleWriter)
+	w.lowWriter, w.consoleWriter = out, errOut
+	testConsoleCalls(log)
+	log.Close()
+
+	stdout, _ := ioutil.ReadFile(out.Name())
+	stderr, _ := ioutil.ReadFile(errOut.Name())
+	if lines := strings.Split(strings.TrimSpace(string(stdout)), "\n"); len(lines) != 3 ||
+		!strings.HasSuffix(lines[0], " - warning") {
+		t.Error("wrong stdout:", string(stdout))
+	}
+	if !strings.HasSuffix(string(stderr), " - error\n") || strings.Count(string(stderr), "\n") != 1 {
+		t.Error("wrong stderr:", string(stderr))
+	}
+}
+
+func TestConsoleInvalid(t *testing.T) {
+	log := NewLogger()
+	cases := map[string]string{
+		`{"writer":"stdin"}`: "writer",
+		`{"writer":""}`:      "writer",
+		`{"writer":"split", "splitlevel":"critical"}`: "splitlevel",
+		`{"color":"rainbow"}`:                         "color",
+	}
+	for helper, field := range cases {
+		err := log.AddAdapter(AdapterConsole, LevelInfoStr, helper)
+		if cErr, ok := err.(*ConfigError); !ok || cErr.Field != field {
+			t.Error(helper, "not get ConfigError on", field, "but", err)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package logs
+
+import (
+	"context"
+	"sync"
+)
+
+// ContextExtractor return fields of ctx, like request id, tenant or user.
+// Nil or empty result means nothing to attach
+type ContextExtractor func(ctx context.Context) Fields
+
+// ContextHook is called with ctx after a record of InfoCtx and so on is
+// written, like to record it as event of the span in ctx
+type ContextHook func(ctx context.Context, level string, message string, fields Fields)
+
+type loggerKey struct{}
+
+// NewContext return a copy of ctx carrying logger
+func NewContext(ctx context.Context, logger *Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+var (
+	defaultLoggerOnce sync.Once
+	defaultLogger     *Logger
+)
+
+// FromContext return logger carried by ctx. If not found, return a default
+// logger write info and above to console, it is created once and shared
+func FromContext(ctx context.Context) *Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*Logger); ok && logger != nil {
+		return logger
+	}
+	defaultLoggerOnce.Do(func() {
+		defaultLogger = NewLoggerWithCmdWriter(LevelInfoStr)
+	})
+	return defaultLogger
+}
+
+// ContextValue return extractor attach ctx.Value(key) as field if it is not nil
+func ContextValue(key interface{}, field string) ContextExtractor {
+	return func(ctx context.Context) Fields {
+		if v := ctx.Value(key); v != nil {
+			return Fields{field: v}
+		}
+		return nil
+	}
+}
+
+// AddContextExtractor add fn to extract fields in InfoCtx and so on.
+// Call it before logging, loggers made by WithFields share them
+func (logger *Logger) AddContextExtractor(fn ContextExtractor) {
+	if logger.parent != nil {
+		logger.parent.AddContextExtractor(fn)
+		return
+	}
+	logger.extractors = append(logger.extractors, fn)
+}
+
+// AddContextHook add fn to be called by InfoCtx and so on.
+// Call it before logging, loggers made by WithFields share them
+func (logger *Logger) AddContextHook(fn ContextHook) {
+	if logger.parent != nil {
+		logger.parent.AddContextHook(fn)
+		return
+	}
+	logger.contextHooks = append(logger.contextHooks, fn)
+}
+
+// runContextHooks call context hooks of root logger if logger is made by WithContext
+func (logger *Logger) runContextHooks(message logMessage) {
+	if logger.ctx == nil || logger.parent == nil {
+		return
+	}
+	for _, fn := range logger.parent.contextHooks {
+		fn(logger.ctx, levelName[message.level], message.message, message.fields)
+	}
+}
+
+// WithContext return a logger attach fields extracted from ctx to every record,
+// fields from ctx override fields of logger with the same key
+func (logger *Logger) WithContext(ctx context.Context) *Logger {
+	root := logger
+	if logger.parent != nil {
+		root = logger.parent
+	}
+
+	var fields Fields
+	for _, fn := range root.extractors {
+		for k, v := range fn(ctx) {
+			if fields == nil {
+				fields = make(Fields)
+			}
+			fields[k] = v
+		}
+	}
+	if len(fields) == 0 && len(root.contextHooks) == 0 {
+		return logger
+	}
+	derived := logger.WithFields(fields)
+	derived.ctx = ctx
+	return derived
+}
+
+// use {} as message place, attach fields extracted from ctx
+func (logger *Logger) DebugCtx(ctx context.Context, message string, args ...interface{}) {
+	logger.WithContext(ctx).saveLog(LevelDebug, message, args...)
+}
+
+func (logger *Logger) InfoCtx(ctx context.Context, message string, args ...interface{}) {
+	logger.WithContext(ctx).saveLog(LevelInfo, message, args...)
+}
+
+func (logger *Logger) WarningCtx(ctx context.Context, message string, args ...interface{}) {
+	logger.WithContext(ctx).saveLog(LevelWarning, message, args...)
+}
+
+func (logger *Logger) ErrorCtx(ctx context.Context, message string, args ...interface{}) {
+	logger.WithContext(ctx).saveLog(LevelError, message, args...)
+}
+
+// use format to parse message, attach fields extracted from ctx
+func (logger *Logger) DebugFCtx(ctx context.Context, message string, args ...interface{}) {
+	logger.WithContext(ctx).saveLogFormat(LevelDebug, message, args...)
+}
+
+func (logger *Logger) InfoFCtx(ctx context.Context, message string, args ...interface{}) {
+	logger.WithContext(ctx).saveLogFormat(LevelInfo, message, args...)
+}
+
+func (logger *Logger) WarningFCtx(ctx context.Context, message string, args ...interface{}) {
+	logger.WithContext(ctx).saveLogFormat(LevelWarning, message, args...)
+}
+
+func (logger *Logger) ErrorFCtx(ctx context.Context, message string, args ...interface{}) {
+	logger.WithContext(ctx).saveLogFormat(LevelError, message, args...)
+}
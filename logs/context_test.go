@@ -0,0 +1,69 @@
+package logs
+
+import (
+	"context"
+	"testing"
+)
+
+type requestIDKey struct{}
+
+func TestContext(t *testing.T) {
+	log := NewLogger()
+	if err := log.AddAdapter(AdapterMemory, LevelTraceStr, `{"name":"context"}`); err != nil {
+		t.Fatal(err)
+	}
+	defer log.Close()
+	log.AddContextExtractor(ContextValue(requestIDKey{}, "request_id"))
+	log.WithFields(Fields{"a": 1}).AddContextExtractor(func(ctx context.Context) Fields {
+		return Fields{"tenant": "box"}
+	})
+
+	ctx := NewContext(context.WithValue(context.Background(), requestIDKey{}, "r1"), log.WithFields(Fields{"tenant": "jan", "user": 2}))
+	FromContext(ctx).InfoCtx(ctx, "login {}", "ok")
+	log.ErrorFCtx(context.Background(), "login %s", "failed")
+
+	records := log.MemoryBuffer("context").Snapshot()
+	if len(records) != 2 {
+		t.Fatal("wrong records:", records)
+	}
+	fields := records[0].Fields
+	if records[0].Message != "login ok" || fields["request_id"] != "r1" || fields["tenant"] != "box" || fields["user"] != 2 {
+		t.Error("wrong first record:", records[0])
+	}
+	if records[0].File != "context_test.go" {
+		t.Error("wrong caller:", records[0].File)
+	}
+	if records[1].Message != "login failed" || len(records[1].Fields) != 1 || records[1].Fields["tenant"] != "box" {
+		t.Error("wrong second record:", records[1])
+	}
+
+	if logger := FromContext(context.Background()); logger == nil || logger == log || logger != FromContext(context.TODO()) {
+		t.Error("wrong default logger:", logger)
+	} else {
+		logger.InfoCtx(ctx, "default logger is usable")
+	}
+}
+
+func TestContextHook(t *testing.T) {
+	log := NewLogger()
+	_ = log.AddAdapter(AdapterMemory, LevelInfoStr, `{"name":"context"}`)
+	defer log.Close()
+
+	type hooked struct {
+		ctx     context.Context
+		level   string
+		message string
+	}
+	var records []hooked
+	log.AddContextHook(func(ctx context.Context, level string, message string, fields Fields) {
+		records = append(records, hooked{ctx, level, message})
+	})
+
+	ctx := context.WithValue(context.Background(), requestIDKey{}, "r1")
+	log.WarningFCtx(ctx, "retry %d", 1)
+	log.Warning("no context")
+
+	if len(records) != 1 || records[0].ctx != ctx || records[0].level != LevelWarningStr || records[0].message != "retry 1" {
+		t.Error("wrong hooked records:", records)
+	}
+}
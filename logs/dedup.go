@@ -0,0 +1,91 @@
+package logs
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// dedup collapse consecutive records with the same level, message and caller
+type dedup struct {
+	sync.Mutex
+	window time.Duration
+
+	// whether a run is started
+	active bool
+	// first record of the current run and the last repeated one
+	first    logMessage
+	last     logMessage
+	repeated int
+	timer    *time.Timer
+	// increased when a run end, so timer of ended run do nothing
+	run int
+}
+
+// SetDedup collapse consecutive records with the same level, message and
+// caller in window into one, followed by "last message repeated N times"
+// when a different record come or window expire. Zero window disable it.
+// Call it before logging, loggers made by WithFields share it
+func (logger *Logger) SetDedup(window time.Duration) {
+	if logger.parent != nil {
+		logger.parent.SetDedup(window)
+		return
+	}
+
+	if logger.dedup != nil {
+		logger.dedup.Lock()
+		logger.dedup.summary(logger)
+		logger.dedup.Unlock()
+	}
+	if window <= 0 {
+		logger.dedup = nil
+		return
+	}
+	logger.dedup = &dedup{window: window}
+}
+
+func sameRecord(a, b logMessage) bool {
+	return a.level == b.level && a.message == b.message && a.trace == b.trace
+}
+
+// write message unless it repeat the current run, must be called by root logger
+func (d *dedup) write(logger *Logger, message logMessage) {
+	d.Lock()
+	defer d.Unlock()
+
+	if d.active && sameRecord(d.first, message) && message.time.Sub(d.first.time) < d.window {
+		d.last = message
+		d.repeated++
+		// summary is written when window of the first record expire
+		if d.timer == nil {
+			run := d.run
+			d.timer = time.AfterFunc(d.first.time.Add(d.window).Sub(time.Now()), func() {
+				d.Lock()
+				if d.run == run {
+					d.summary(logger)
+				}
+				d.Unlock()
+			})
+		}
+		return
+	}
+
+	d.summary(logger)
+	d.active, d.first = true, message
+	logger.write(message)
+}
+
+// summary write repeated times of current run and end it, must hold lock
+func (d *dedup) summary(logger *Logger) {
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer = nil
+	}
+	if d.repeated > 0 {
+		message := d.last
+		message.message = fmt.Sprintf("last message repeated %d times", d.repeated)
+		logger.write(message)
+	}
+	d.active, d.first, d.last, d.repeated = false, logMessage{}, logMessage{}, 0
+	d.run++
+}
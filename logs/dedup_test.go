@@ -0,0 +1,75 @@
+package logs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDedup(t *testing.T) {
+	log := NewLogger()
+	if err := log.AddAdapter(AdapterMemory, LevelInfoStr, `{"name":"dedup"}`); err != nil {
+		t.Fatal(err)
+	}
+	log.SetDedup(time.Hour)
+
+	child := log.WithFields(Fields{"a": 1})
+	for i := 0; i < 5; i++ {
+		child.Error("connect failed")
+	}
+	log.Error("connect failed")
+	for i := 0; i < 3; i++ {
+		log.Info("retry {}", 1)
+	}
+	log.Warning("give up")
+	log.Close()
+
+	var messages []string
+	for _, record := range log.MemoryBuffer("dedup").Snapshot() {
+		messages = append(messages, record.Level+" "+record.Message)
+	}
+	expected := []string{
+		LevelErrorStr + " connect failed",
+		LevelErrorStr + " last message repeated 4 times",
+		// different caller
+		LevelErrorStr + " connect failed",
+		LevelInfoStr + " retry 1",
+		LevelInfoStr + " last message repeated 2 times",
+		LevelWarningStr + " give up",
+	}
+	if len(messages) != len(expected) {
+		t.Fatal("wrong records:", messages)
+	}
+	for i := range expected {
+		if messages[i] != expected[i] {
+			t.Errorf("record %d: expect %q, got %q", i, expected[i], messages[i])
+		}
+	}
+}
+
+func TestDedupWindow(t *testing.T) {
+	log := NewLogger()
+	_ = log.AddAdapter(AdapterMemory, LevelInfoStr, `{"name":"dedup"}`)
+	defer log.Close()
+	log.SetDedup(50 * time.Millisecond)
+
+	buffer := log.MemoryBuffer("dedup")
+	for i := 0; i < 3; i++ {
+		log.Info("tick")
+	}
+	if n := len(buffer.Snapshot()); n != 1 {
+		t.Error("repeated records written:", n)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	records := buffer.Snapshot()
+	if len(records) != 2 || records[1].Message != "last message repeated 2 times" {
+		t.Fatal("summary not written when window expire:", records)
+	}
+
+	for i := 0; i < 2; i++ {
+		log.Info("tick")
+	}
+	if records = buffer.Snapshot(); len(records) != 3 || records[2].Message != "tick" {
+		t.Error("new run not started:", records)
+	}
+}
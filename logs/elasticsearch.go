@@ -0,0 +1,268 @@
+package logs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const esDatePlaceholder = "{date}"
+
+// BulkError report documents rejected by elasticsearch bulk api
+type BulkError struct {
+	// Failed documents count and the reason of first one
+	Failed int
+	Status int
+	Reason string
+}
+
+func (e *BulkError) Error() string {
+	return fmt.Sprintf("elasticsearch adapter: %d documents failed, first one status %d: %s",
+		e.Failed, e.Status, e.Reason)
+}
+
+type esWriter struct {
+	// first field to make sure 64-bit aligned
+	counter deliveryCounter
+
+	level int
+
+	// Elasticsearch or OpenSearch address like "http://localhost:9200"
+	URL      string            `json:"url"`
+	Username string            `json:"username"`
+	Password string            `json:"password"`
+	APIKey   string            `json:"apikey"`
+	Headers  map[string]string `json:"headers"`
+	Gzip     bool              `json:"gzip"`
+	Timeout  Duration          `json:"timeout"`
+	tlsOptions
+
+	// Index name, "{date}" is replaced by record date in DateFormat
+	Index      string `json:"index"`
+	DateFormat string `json:"dateformat"`
+	// "index" or "create", data stream only accept create
+	OpType string `json:"optype"`
+
+	// Retry failed documents on 429, 5xx and network error
+	batchOptions
+
+	bulkURL string
+	client  *http.Client
+	batcher *batcher
+	errorReporter
+}
+
+// esDocument is jsonRecord with time in @timestamp
+type esDocument struct {
+	Timestamp string `json:"@timestamp"`
+	*jsonRecord
+}
+
+// esBulkResponse is the part of bulk response needed
+type esBulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Status int             `json:"status"`
+		Error  json.RawMessage `json:"error"`
+	} `json:"items"`
+}
+
+func (w *esWriter) WriteMsg(message logMessage) error {
+	if message.level < w.level {
+		return nil
+	}
+
+	err := w.batcher.add(batchRecord{message: message, data: w.bulkItem(message)})
+	if err != nil {
+		atomic.AddUint64(&w.counter.dropped, 1)
+	}
+	return err
+}
+
+func (w *esWriter) Flush() {
+	w.batcher.flush()
+}
+
+func (w *esWriter) Destroy() {
+	w.batcher.close()
+}
+
+func (w *esWriter) reportError(err error) {
+	w.counter.setError(err)
+	w.errorReporter.reportError(err)
+}
+
+func (w *esWriter) deliveryStats() DeliveryStats {
+	return w.counter.stats(AdapterElasticsearch)
+}
+
+// bulkItem return the action and document lines of message
+func (w *esWriter) bulkItem(message logMessage) []byte {
+	index := strings.Replace(w.Index, esDatePlaceholder, message.time.UTC().Format(w.DateFormat), -1)
+	action, _ := json.Marshal(map[string]map[string]string{w.OpType: {"_index": index}})
+
+	record := newJSONRecord(w.level, message)
+	doc := esDocument{Timestamp: record.Time, jsonRecord: &record}
+	record.Time = ""
+
+	return append(append(append(action, '\n'), marshalJSONRecord(&doc, &record)...), '\n')
+}
+
+// send is called by batcher in its goroutine, only failed documents are retried
+func (w *esWriter) send(batch []batchRecord) {
+	pending := batch
+	err := w.retry(&w.counter, func() (bool, error) {
+		retry, rejected, retryable, err := w.bulk(pending)
+		if err != nil {
+			return retryable, err
+		}
+
+		sent := len(pending) - len(retry)
+		if rejected != nil {
+			sent -= rejected.Failed
+			atomic.AddUint64(&w.counter.failed, uint64(rejected.Failed))
+			w.reportError(rejected)
+		}
+		atomic.AddUint64(&w.counter.sent, uint64(sent))
+
+		if pending = retry; len(pending) > 0 {
+			return true, fmt.Errorf("elasticsearch adapter: %d documents need retry", len(pending))
+		}
+		return false, nil
+	})
+
+	if err != nil {
+		atomic.AddUint64(&w.counter.failed, uint64(len(pending)))
+		w.reportError(err)
+		return
+	}
+	atomic.AddUint64(&w.counter.batches, 1)
+}
+
+// bulk send records, return records need retry and documents rejected.
+// err is set when the whole request failed
+func (w *esWriter) bulk(records []batchRecord) (retry []batchRecord, rejected *BulkError, retryable bool, err error) {
+	var body []byte
+	for _, record := range records {
+		body = append(body, record.data...)
+	}
+	if w.Gzip {
+		if body, err = gzipBytes(body); err != nil {
+			return
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodPost, w.bulkURL, bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	if w.Gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	if w.APIKey != "" {
+		req.Header.Set("Authorization", "ApiKey "+w.APIKey)
+	} else if w.Username != "" {
+		req.SetBasicAuth(w.Username, w.Password)
+	}
+	setHTTPHeaders(req, w.Headers)
+
+	respBody, retryable, err := doHTTP(w.client, req)
+	if err != nil {
+		return
+	}
+
+	var resp esBulkResponse
+	if err = json.Unmarshal(respBody, &resp); err != nil {
+		err = fmt.Errorf("elasticsearch adapter: invalid bulk response: %s", err)
+		return
+	}
+	if !resp.Errors {
+		return
+	}
+
+	for i, item := range resp.Items {
+		if i >= len(records) {
+			break
+		}
+		// only one key, the op type
+		for _, result := range item {
+			switch {
+			case result.Status < 300:
+			case result.Status == http.StatusTooManyRequests || result.Status >= 500:
+				retry = append(retry, records[i])
+			default:
+				if rejected == nil {
+					rejected = &BulkError{Status: result.Status, Reason: string(result.Error)}
+				}
+				rejected.Failed++
+			}
+		}
+	}
+	return
+}
+
+func newElasticsearchAdapter(level string, helper string) (writer *esWriter, err error) {
+	w := getEsWriter()
+
+	if err = decodeHelper(AdapterElasticsearch, helper, w); err != nil {
+		return
+	}
+
+	if w.level = getLevelInt(level); w.level == -1 {
+		err = NoSupportLevel
+		return
+	}
+
+	if err = w.validate(); err != nil {
+		return
+	}
+
+	tlsConfig, err := w.tlsOptions.tlsConfig(AdapterElasticsearch)
+	if err != nil {
+		return
+	}
+	w.client = newHTTPClient(tlsConfig, time.Duration(w.Timeout))
+	w.batcher = w.newBatcher(w.send)
+
+	writer = w
+	return
+}
+
+func (w *esWriter) validate() error {
+	u, err := url.Parse(w.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return newConfigError(AdapterElasticsearch, "url", "%q is not a http or https url", w.URL)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/_bulk"
+	w.bulkURL = u.String()
+
+	if w.Index == "" {
+		return newConfigError(AdapterElasticsearch, "index", "must not be empty")
+	}
+	if w.DateFormat == "" {
+		return newConfigError(AdapterElasticsearch, "dateformat", "must not be empty")
+	}
+	switch w.OpType {
+	case "index", "create":
+	default:
+		return newConfigError(AdapterElasticsearch, "optype", "unknown op type %q", w.OpType)
+	}
+	return w.batchOptions.validate(AdapterElasticsearch)
+}
+
+func getEsWriter() *esWriter {
+	return &esWriter{
+		Timeout:      Duration(10 * time.Second),
+		Index:        "logs-" + esDatePlaceholder,
+		DateFormat:   "2006.01.02",
+		OpType:       "index",
+		batchOptions: defaultBatchOptions(),
+		level:        LevelInfo,
+	}
+}
@@ -0,0 +1,139 @@
+package logs
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeBulk answer bulk requests, item status are taken from status in order, empty means 201
+type fakeBulk struct {
+	sync.Mutex
+	indices  []string
+	messages []string
+	auth     []string
+	status   []int
+}
+
+func (b *fakeBulk) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b.Lock()
+	defer b.Unlock()
+
+	if r.URL.Path != "/_bulk" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	b.auth = append(b.auth, r.Header.Get("Authorization"))
+
+	var items []map[string]interface{}
+	hasError := false
+	s := bufio.NewScanner(r.Body)
+	for s.Scan() {
+		var action map[string]map[string]string
+		_ = json.Unmarshal(s.Bytes(), &action)
+		if !s.Scan() {
+			break
+		}
+
+		status := http.StatusCreated
+		if len(b.status) > 0 {
+			status, b.status = b.status[0], b.status[1:]
+		}
+		result := map[string]interface{}{"status": status}
+		if status >= 300 {
+			hasError = true
+			result["error"] = map[string]string{"type": "mapper_parsing_exception"}
+		} else {
+			var doc jsonRecord
+			_ = json.Unmarshal(s.Bytes(), &doc)
+			b.indices = append(b.indices, action["index"]["_index"])
+			b.messages = append(b.messages, doc.Message)
+		}
+		items = append(items, map[string]interface{}{"index": result})
+	}
+
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"errors": hasError, "items": items})
+}
+
+func TestElasticsearchBulk(t *testing.T) {
+	bulk := &fakeBulk{}
+	server := httptest.NewServer(bulk)
+	defer server.Close()
+
+	log := NewLogger()
+	err := log.AddAdapter(AdapterElasticsearch, LevelInfoStr, `{"url":"`+server.URL+
+		`", "index":"app-{date}", "dateformat":"2006.01", "apikey":"key"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testFileCalls(log)
+	log.Close()
+
+	if messages := strings.Join(bulk.messages, ","); messages != "error,warning,info" {
+		t.Error("wrong messages:", messages)
+	}
+	if index := "app-" + time.Now().UTC().Format("2006.01"); bulk.indices[0] != index {
+		t.Error("wrong index:", bulk.indices[0], "not", index)
+	}
+	if bulk.auth[0] != "ApiKey key" {
+		t.Error("wrong auth:", bulk.auth[0])
+	}
+}
+
+func TestElasticsearchPartialFailure(t *testing.T) {
+	bulk := &fakeBulk{status: []int{http.StatusCreated, http.StatusTooManyRequests, http.StatusBadRequest}}
+	server := httptest.NewServer(bulk)
+	defer server.Close()
+
+	log := NewLogger()
+	errs := make(chan error, 8)
+	log.OnError(func(err error) {
+		errs <- err
+	})
+	err := log.AddAdapter(AdapterElasticsearch, LevelInfoStr, `{"url":"`+server.URL+
+		`", "backoff":"1ms", "username":"box", "password":"jan"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log.Info("ok")
+	log.Info("limited")
+	log.Info("bad")
+	log.Close()
+
+	// only the limited one is sent again
+	if len(bulk.auth) != 2 || !strings.HasPrefix(bulk.auth[0], "Basic ") {
+		t.Error("wrong requests:", bulk.auth)
+	}
+	if messages := strings.Join(bulk.messages, ","); messages != "ok,limited" {
+		t.Error("wrong messages:", messages)
+	}
+	stats := log.DeliveryStats()
+	if len(stats) != 1 || stats[0].Sent != 2 || stats[0].Failed != 1 || stats[0].Retries != 1 {
+		t.Error("wrong stats:", stats)
+	}
+	if e, ok := (<-errs).(*BulkError); !ok || e.Failed != 1 || e.Status != http.StatusBadRequest {
+		t.Error("not get BulkError")
+	}
+}
+
+func TestElasticsearchInvalid(t *testing.T) {
+	log := NewLogger()
+	cases := map[string]string{
+		`{"url":"localhost:9200"}`:                           "url",
+		`{"url":"http://localhost:9200", "index":""}`:        "index",
+		`{"url":"http://localhost:9200", "dateformat":""}`:   "dateformat",
+		`{"url":"http://localhost:9200", "optype":"update"}`: "optype",
+		`{"url":"http://localhost:9200", "batchsize":0}`:     "batchsize",
+	}
+	for helper, field := range cases {
+		err := log.AddAdapter(AdapterElasticsearch, LevelInfoStr, helper)
+		if cErr, ok := err.(*ConfigError); !ok || cErr.Field != field {
+			t.Error(helper, "not get ConfigError on", field, "but", err)
+		}
+	}
+}
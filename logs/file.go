@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -32,8 +33,8 @@ type fileWriter struct {
 	MaxLines         int `json:"maxlines"`
 	maxLinesCurLines int
 
-	// Rotate at size
-	MaxSize        int `json:"maxsize"`
+	// Rotate at size, can be bytes or string like "100MB"
+	MaxSize        ByteSize `json:"maxsize"`
 	maxSizeCurSize int
 
 	// Rotate daily
@@ -42,51 +43,98 @@ type fileWriter struct {
 	dailyOpenTime time.Time
 
 	Rotate bool `json:"rotate"`
+
+	// Permissions in octal string, like "0640"
+	Perm        string `json:"perm"`
+	RotatedPerm string `json:"rotatedperm"`
+	DirPerm     string `json:"dirperm"`
+	perm        os.FileMode
+	rotatedPerm os.FileMode
+	dirPerm     os.FileMode
+
+	// Owner of log files and created directory, -1 means not change
+	Uid int `json:"uid"`
+	Gid int `json:"gid"`
+
+	// Called in a new goroutine after each rotation
+	rotateHook func(RotateEvent)
+	errorReporter
+}
+
+// RotateEvent describe a finished rotation of file adapter
+type RotateEvent struct {
+	// OldPath is the file written before rotation, NewPath is where it was renamed to
+	OldPath string
+	NewPath string
+	Size    int
+	Lines   int
+	Reason  string
 }
 
+const (
+	RotateReasonDaily  = "daily"
+	RotateReasonSize   = "size"
+	RotateReasonLines  = "lines"
+	RotateReasonManual = "manual"
+)
+
 func (w *fileWriter) WriteMsg(message logMessage) (err error) {
 	if message.level < w.level {
 		return nil
 	}
 
-	if w.needRotate() {
-		w.Lock()
-		err = w.doRotate()
-		w.Unlock()
-	}
+	return w.writeString(textMessage(w.level, message))
+}
 
-	var msg string
-	if w.level == LevelTrace {
-		msg = fmt.Sprintf("%s %s [%s] [%s:%d] - %s\n", message.timeString, levelString[message.level],
-			message.trace.funcName, message.trace.file, message.trace.line, message.message)
-	} else {
-		msg = fmt.Sprintf("%s %s - %v\n", message.timeString, levelString[message.level], message.message)
+// writeString write msg as it is, rotate before write if need
+func (w *fileWriter) writeString(msg string) (err error) {
+	w.Lock()
+	defer w.Unlock()
+
+	// rotation error is reported to error hook, the message still need write
+	if reason := w.rotateReason(); reason != "" {
+		_ = w.doRotate(reason)
 	}
 
-	w.Lock()
-	_, err = w.fileWriter.Write([]byte(msg))
-	w.Unlock()
+	if w.fileWriter == nil {
+		// reopen failed last time, try again
+		err = w.startLog()
+	}
+	if err == nil {
+		_, err = w.fileWriter.Write([]byte(msg))
+	}
 	if err == nil {
 		w.maxLinesCurLines++
 		w.maxSizeCurSize += len(msg)
 	}
-
 	return
-
 }
 
 func (w *fileWriter) Flush() {
-	_ = w.fileWriter.Sync()
+	w.Lock()
+	defer w.Unlock()
+	if w.fileWriter != nil {
+		_ = w.fileWriter.Sync()
+	}
 }
 
 func (w *fileWriter) Destroy() {
-	_ = w.fileWriter.Close()
+	w.Lock()
+	defer w.Unlock()
+	if w.fileWriter != nil {
+		w.saveLineState()
+		_ = w.fileWriter.Close()
+		w.fileWriter = nil
+	}
 }
 
 func newFileAdapter(level string, helper string) (writer *fileWriter, err error) {
 	w := getFileWrite()
 
-	if err = json.Unmarshal([]byte(helper), &w); err != nil {
+	if err = decodeHelper(AdapterFile, helper, w); err != nil {
+		return
+	}
+	if err = w.validate(); err != nil {
 		return
 	}
 	w.fileExt = filepath.Ext(w.Filename)
@@ -97,6 +145,10 @@ func newFileAdapter(level string, helper string) (writer *fileWriter, err error)
 		return
 	}
 
+	if err = w.parsePerm(); err != nil {
+		return
+	}
+
 	err = w.startLog()
 	if err != nil {
 		return
@@ -106,13 +158,92 @@ func newFileAdapter(level string, helper string) (writer *fileWriter, err error)
 	return
 }
 
+func (w *fileWriter) validate() error {
+	if w.Filename == "" {
+		return newConfigError(AdapterFile, "filename", "must not be empty")
+	}
+	if w.MaxLines < 0 {
+		return newConfigError(AdapterFile, "maxlines", "must not be negative")
+	}
+	return nil
+}
+
+func (w *fileWriter) parsePerm() (err error) {
+	if w.perm, err = parseFileMode("perm", w.Perm); err != nil {
+		return
+	}
+	if w.rotatedPerm, err = parseFileMode("rotatedperm", w.RotatedPerm); err != nil {
+		return
+	}
+	if w.dirPerm, err = parseFileMode("dirperm", w.DirPerm); err != nil {
+		return
+	}
+	if w.Uid < -1 {
+		return newConfigError(AdapterFile, "uid", "%d is not a valid uid", w.Uid)
+	}
+	if w.Gid < -1 {
+		return newConfigError(AdapterFile, "gid", "%d is not a valid gid", w.Gid)
+	}
+	return
+}
+
+func parseFileMode(name string, perm string) (os.FileMode, error) {
+	mode, err := strconv.ParseUint(perm, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, newConfigError(AdapterFile, name, "%q is not octal permission like \"0640\"", perm)
+	}
+	return os.FileMode(mode), nil
+}
+
+func (w *fileWriter) needChown() bool {
+	return w.Uid != -1 || w.Gid != -1
+}
+
+// makeDir create dir and its missing parents, every created one get dirPerm and owner
+func (w *fileWriter) makeDir(dir string) error {
+	var missing []string
+	for d := filepath.Clean(dir); ; d = filepath.Dir(d) {
+		if _, err := os.Stat(d); err == nil || !os.IsNotExist(err) {
+			break
+		}
+		missing = append(missing, d)
+		if filepath.Dir(d) == d {
+			break
+		}
+	}
+
+	for i := len(missing) - 1; i >= 0; i-- {
+		d := missing[i]
+		if err := os.Mkdir(d, w.dirPerm); err != nil {
+			// created by others at the same time
+			if os.IsExist(err) {
+				continue
+			}
+			return err
+		}
+		// Same as file, `os.Mkdir` will obey umask
+		_ = os.Chmod(d, w.dirPerm)
+		if w.needChown() {
+			if err := os.Chown(d, w.Uid, w.Gid); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 func (w *fileWriter) startLog() (err error) {
 
-	if err = os.MkdirAll(filepath.Dir(w.Filename), os.FileMode(0755)); err != nil {
+	if err = w.makeDir(filepath.Dir(w.Filename)); err != nil {
 		return
 	}
 
+	if err = w.recoverRotate(); err != nil {
+		w.reportError(err)
+	}
+
 	if w.fileWriter, err = w.OpenFile(); err != nil {
+		w.fileWriter = nil
 		return
 	}
 
@@ -130,16 +261,19 @@ func (w *fileWriter) initFd() error {
 	w.maxSizeCurSize = int(fInfo.Size())
 	w.maxLinesCurLines = 0
 
-	if fInfo.Size() > 0 {
-		count, err := w.lines()
-		if err != nil {
-			return err
+	// counting lines need read the whole file, only do it when rotate by lines
+	if fInfo.Size() > 0 && w.countLines() {
+		count, ok := w.loadLineState(fInfo)
+		if !ok {
+			if count, err = w.lines(); err != nil {
+				return err
+			}
 		}
 		w.maxLinesCurLines = count
 	}
 
-	if w.needRotate() {
-		return w.doRotate()
+	if reason := w.rotateReason(); reason != "" {
+		return w.doRotate(reason)
 	}
 	return nil
 
@@ -151,13 +285,85 @@ func (w *fileWriter) OpenFile() (*os.File, error) {
 	w.dailyString = w.dailyOpenTime.Format("2006-01-02")
 	w.writeFileName = w.getWriteFileName()
 
-	fd, err := os.OpenFile(w.writeFileName, os.O_WRONLY|os.O_APPEND|os.O_CREATE, os.FileMode(0664))
-	if err == nil {
-		// Make sure file perm is user set perm cause of `os.OpenFile` will obey umask
-		_ = os.Chmod(w.writeFileName, os.FileMode(0664))
+	fd, err := os.OpenFile(w.writeFileName, os.O_WRONLY|os.O_APPEND|os.O_CREATE, w.perm)
+	if err != nil {
+		return fd, err
+	}
+	// Make sure file perm is user set perm cause of `os.OpenFile` will obey umask
+	_ = os.Chmod(w.writeFileName, w.perm)
+
+	if w.needChown() {
+		if err = os.Chown(w.writeFileName, w.Uid, w.Gid); err != nil {
+			_ = fd.Close()
+			return nil, err
+		}
 	}
 
-	return fd, err
+	return fd, nil
+}
+
+func (w *fileWriter) countLines() bool {
+	return w.Rotate && w.MaxLines > 0
+}
+
+// Files smaller than it are cheap to count, no line state file for them
+const lineStateMinSize = 1 << 20
+
+// lineState is saved beside the log file, so restart no need count lines again
+type lineState struct {
+	Size    int64 `json:"size"`
+	ModTime int64 `json:"mtime"`
+	Lines   int   `json:"lines"`
+}
+
+// sidecarName return a hidden file beside fileName
+func sidecarName(fileName string, suffix string) string {
+	dir, base := filepath.Split(fileName)
+	return filepath.Join(dir, "."+base+suffix)
+}
+
+func lineStateName(fileName string) string {
+	return sidecarName(fileName, ".state")
+}
+
+// loadLineState return the saved line count, only if the file not changed since saved
+func (w *fileWriter) loadLineState(fInfo os.FileInfo) (int, bool) {
+	data, err := ioutil.ReadFile(lineStateName(w.writeFileName))
+	if err != nil {
+		return 0, false
+	}
+	var state lineState
+	if err = json.Unmarshal(data, &state); err != nil {
+		return 0, false
+	}
+	if state.Size != fInfo.Size() || state.ModTime != fInfo.ModTime().UnixNano() {
+		return 0, false
+	}
+	return state.Lines, true
+}
+
+func (w *fileWriter) saveLineState() {
+	stateName := lineStateName(w.writeFileName)
+	if !w.countLines() || w.fileWriter == nil {
+		return
+	}
+	_ = w.fileWriter.Sync()
+
+	fInfo, err := w.fileWriter.Stat()
+	if err != nil {
+		// keep the state saved before, the file may be closed
+		return
+	}
+	if fInfo.Size() < lineStateMinSize {
+		_ = os.Remove(stateName)
+		return
+	}
+	data, _ := json.Marshal(lineState{
+		Size:    fInfo.Size(),
+		ModTime: fInfo.ModTime().UnixNano(),
+		Lines:   w.maxLinesCurLines,
+	})
+	_ = ioutil.WriteFile(stateName, data, w.perm)
 }
 
 func (w *fileWriter) lines() (int, error) {
@@ -187,9 +393,95 @@ func (w *fileWriter) lines() (int, error) {
 	return count, nil
 }
 
-func (w *fileWriter) doRotate() (err error) {
+func (w *fileWriter) setRotateHook(fn func(RotateEvent)) {
+	w.Lock()
+	w.rotateHook = fn
+	w.Unlock()
+}
+
+func (w *fileWriter) forceRotate() error {
+	w.Lock()
+	defer w.Unlock()
+	return w.doRotate(RotateReasonManual)
+}
+
+// RotateError is reported to error hook when rotation failed
+type RotateError struct {
+	OldPath string
+	NewPath string
+	Err     error
+}
+
+func (e *RotateError) Error() string {
+	return fmt.Sprintf("rotate %s to %s: %s", e.OldPath, e.NewPath, e.Err)
+}
+
+// rotateJournal record a rotation in progress, if the process died before
+// rotation finish, startLog will use it to complete or roll back the rotation
+type rotateJournal struct {
+	OldPath string `json:"old"`
+	NewPath string `json:"new"`
+	Reason  string `json:"reason"`
+}
+
+func (w *fileWriter) journalName() string {
+	return sidecarName(w.Filename, ".rotate")
+}
+
+func (w *fileWriter) writeJournal(journal rotateJournal) error {
+	data, _ := json.Marshal(journal)
+	fd, err := os.OpenFile(w.journalName(), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, w.perm)
+	if err != nil {
+		return err
+	}
+	if _, err = fd.Write(data); err == nil {
+		err = fd.Sync()
+	}
+	if cErr := fd.Close(); err == nil {
+		err = cErr
+	}
+	return err
+}
+
+// recoverRotate finish the rotation left by last process
+func (w *fileWriter) recoverRotate() error {
+	data, err := ioutil.ReadFile(w.journalName())
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	var journal rotateJournal
+	if err = json.Unmarshal(data, &journal); err == nil && journal.OldPath != "" && journal.NewPath != "" {
+		_, oldErr := os.Stat(journal.OldPath)
+		_, newErr := os.Stat(journal.NewPath)
+		switch {
+		case oldErr == nil && os.IsNotExist(newErr):
+			// died before rename, roll forward
+			if err = os.Rename(journal.OldPath, journal.NewPath); err != nil {
+				err = &RotateError{OldPath: journal.OldPath, NewPath: journal.NewPath, Err: err}
+				break
+			}
+			_ = os.Remove(lineStateName(journal.OldPath))
+			_ = os.Chmod(journal.NewPath, w.rotatedPerm)
+		case newErr == nil:
+			// died after rename, only perm left
+			// if old file also exists the target was taken by others, keep old file as it is
+			if os.IsNotExist(oldErr) {
+				_ = os.Chmod(journal.NewPath, w.rotatedPerm)
+			}
+		}
+	}
+
+	if rmErr := os.Remove(w.journalName()); err == nil {
+		err = rmErr
+	}
+	return err
+}
+
+func (w *fileWriter) doRotate(reason string) (err error) {
 
-	_ = w.fileWriter.Close()
 	// use date-timestamp to rename old file
 	var fName string
 	if time.Now().Unix() == w.dailyOpenTime.Unix() {
@@ -201,36 +493,83 @@ func (w *fileWriter) doRotate() (err error) {
 		fName = w.filenameOnly + "-" + w.dailyString + "-" + strconv.FormatInt(w.dailyOpenTime.Unix(), 10) + w.fileExt
 	}
 
-	err = os.Rename(w.writeFileName, fName)
+	oldName := w.writeFileName
+	event := RotateEvent{
+		OldPath: oldName,
+		NewPath: fName,
+		Size:    w.maxSizeCurSize,
+		Lines:   w.maxLinesCurLines,
+		Reason:  reason,
+	}
 
-	if err != nil {
-		goto RESTART_LOG
+	if err = w.writeJournal(rotateJournal{OldPath: oldName, NewPath: fName, Reason: reason}); err != nil {
+		// can not rotate safely, keep writing the old file
+		err = &RotateError{OldPath: oldName, NewPath: fName, Err: err}
+		w.reportError(err)
+		return
 	}
-	err = os.Chmod(fName, os.FileMode(0444))
 
-RESTART_LOG:
-	w.startLog()
+	if w.fileWriter != nil {
+		_ = w.fileWriter.Close()
+		w.fileWriter = nil
+	}
+	// the state is of the old file, will be wrong for new one
+	_ = os.Remove(lineStateName(oldName))
+
+	if err = os.Rename(oldName, fName); err != nil {
+		// roll back, reopen the old file
+		_ = os.Remove(w.journalName())
+		err = &RotateError{OldPath: oldName, NewPath: fName, Err: err}
+		w.reportError(err)
+		if sErr := w.startLog(); sErr != nil {
+			w.reportError(sErr)
+		}
+		return
+	}
+	_ = os.Chmod(fName, w.rotatedPerm)
+	_ = os.Remove(w.journalName())
+
+	if w.rotateHook != nil {
+		go w.rotateHook(event)
+	}
 
+	if err = w.startLog(); err != nil {
+		w.reportError(err)
+	}
 	return
 }
 
-func (w *fileWriter) needRotate() bool {
-
-	return w.Rotate && ((w.Daily && w.dailyString != time.Now().Format("2006-01-02")) ||
-		(w.MaxSize > 0 && w.maxSizeCurSize >= w.MaxSize) ||
-		(w.MaxLines > 0 && w.maxLinesCurLines >= w.MaxLines))
+// rotateReason return why the file need rotate, empty means no need, must hold lock
+func (w *fileWriter) rotateReason() string {
+	if !w.Rotate {
+		return ""
+	}
+	switch {
+	case w.Daily && w.dailyString != time.Now().Format("2006-01-02"):
+		return RotateReasonDaily
+	case w.MaxSize > 0 && ByteSize(w.maxSizeCurSize) >= w.MaxSize:
+		return RotateReasonSize
+	case w.MaxLines > 0 && w.maxLinesCurLines >= w.MaxLines:
+		return RotateReasonLines
+	}
+	return ""
 }
 
 func getFileWrite() *fileWriter {
 	return &fileWriter{
-		Daily:    true,
-		Filename: "app.log",
-		Rotate:   true,
-		level:    LevelInfo,
+		Daily:       true,
+		Filename:    "app.log",
+		Rotate:      true,
+		Perm:        "0664",
+		RotatedPerm: "0444",
+		DirPerm:     "0755",
+		Uid:         -1,
+		Gid:         -1,
+		level:       LevelInfo,
 	}
 }
 
-func (w fileWriter) getWriteFileName() string {
+func (w *fileWriter) getWriteFileName() string {
 	if w.Rotate {
 		return w.filenameOnly + "-" + w.dailyString + w.fileExt
 	} else {
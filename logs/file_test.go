@@ -2,10 +2,13 @@ package logs
 
 import (
 	"bufio"
+	"encoding/json"
 	"io/ioutil"
 	"os"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
 func testFileCalls(log *Logger) {
@@ -191,3 +194,315 @@ func TestAsyncMultipleFileWriter(t *testing.T) {
 
 	_ = os.RemoveAll("./async/")
 }
+
+func TestRotateHook(t *testing.T) {
+	log := NewLogger()
+	events := make(chan RotateEvent, 8)
+	log.OnRotate(func(e RotateEvent) {
+		events <- e
+	})
+	_ = log.AddAdapter("file", "trace", `{"filename":"./hook/hook.log", "rotate":false}`)
+
+	testFileCalls(log)
+	if err := log.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case e := <-events:
+		if e.Reason != RotateReasonManual {
+			t.Error(e.Reason, "not "+RotateReasonManual)
+		}
+		if e.OldPath != "./hook/hook.log" {
+			t.Error(e.OldPath, "not ./hook/hook.log")
+		}
+		if e.Lines != LevelError {
+			t.Error(e.Lines, "not "+strconv.Itoa(LevelError)+" lines")
+		}
+		if _, err := os.Stat(e.NewPath); err != nil {
+			t.Error(err)
+		}
+	case <-time.After(time.Second):
+		t.Error("no rotate event received")
+	}
+	log.Close()
+
+	files, _ := ioutil.ReadDir("./hook/")
+	expected := 2
+	if len(files) != expected {
+		t.Error(len(files), "not "+strconv.Itoa(expected)+" file")
+	}
+
+	_ = os.RemoveAll("./hook/")
+}
+
+// Rotate from another goroutine, like a SIGHUP handler, while logging async
+func TestAsyncRotateConcurrent(t *testing.T) {
+	log := NewLogger()
+	_ = log.AddAdapter("file", "trace", `{"filename":"./concurrent/concurrent.log", "maxlines":50}`)
+	log.Async()
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 10; i++ {
+			_ = log.Rotate()
+		}
+		close(done)
+	}()
+	for i := 0; i < 100; i++ {
+		testFileCalls(log)
+	}
+	<-done
+	log.Close()
+
+	_ = os.RemoveAll("./concurrent/")
+}
+
+func TestFilePerm(t *testing.T) {
+	log := NewLogger()
+	err := log.AddAdapter("file", "trace",
+		`{"filename":"./perm/perm.log", "rotate":false, "perm":"0640", "rotatedperm":"0400", "dirperm":"0750"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testFileCalls(log)
+	if err := log.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	log.Close()
+
+	files, _ := ioutil.ReadDir("./perm/")
+	for _, f := range files {
+		expected := os.FileMode(0400)
+		if f.Name() == "perm.log" {
+			expected = 0640
+		}
+		if f.Mode().Perm() != expected {
+			t.Error(f.Name(), f.Mode().Perm(), "not", expected)
+		}
+	}
+	if d, err := os.Stat("./perm/"); err != nil || d.Mode().Perm() != 0750 {
+		t.Error("dir perm not 0750")
+	}
+
+	_ = os.RemoveAll("./perm/")
+}
+
+func TestFileDirPermNested(t *testing.T) {
+	log := NewLogger()
+	err := log.AddAdapter("file", "trace",
+		`{"filename":"./perm-nested/a/b/nested.log", "rotate":false, "dirperm":"0700", "uid":`+strconv.Itoa(os.Getuid())+`}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testFileCalls(log)
+	log.Close()
+
+	for _, dir := range []string{"./perm-nested/", "./perm-nested/a/", "./perm-nested/a/b/"} {
+		if d, err := os.Stat(dir); err != nil || d.Mode().Perm() != 0700 {
+			t.Error(dir, "perm not 0700")
+		}
+	}
+
+	_ = os.RemoveAll("./perm-nested/")
+}
+
+func TestFilePermInvalid(t *testing.T) {
+	log := NewLogger()
+	for _, helper := range []string{
+		`{"filename":"./perm-invalid/a.log", "perm":"rw-r--r--"}`,
+		`{"filename":"./perm-invalid/a.log", "rotatedperm":"0999"}`,
+		`{"filename":"./perm-invalid/a.log", "dirperm":"01777"}`,
+		`{"filename":"./perm-invalid/a.log", "uid":-2}`,
+	} {
+		if log.AddAdapter("file", "trace", helper) == nil {
+			t.Error(helper, "should be rejected")
+		}
+	}
+	_ = os.RemoveAll("./perm-invalid/")
+}
+
+func TestFileHelperInvalid(t *testing.T) {
+	log := NewLogger()
+	cases := map[string]string{
+		`{"filename":"./invalid/a.log", "maxsise":1024}`:  "maxsise",
+		`{"filename":"./invalid/a.log", "maxsize":"1QB"}`: "maxsize",
+		`{"filename":""}`: "filename",
+	}
+	for helper, field := range cases {
+		err := log.AddAdapter("file", "trace", helper)
+		if cErr, ok := err.(*ConfigError); !ok || cErr.Field != field {
+			t.Error(helper, "not get ConfigError on", field, "but", err)
+		}
+	}
+	_ = os.RemoveAll("./invalid/")
+}
+
+func TestLineState(t *testing.T) {
+	_ = os.MkdirAll("./linestate/", 0755)
+	fileName := "./linestate/state-" + time.Now().Format("2006-01-02") + ".log"
+	line := strings.Repeat("x", 1023) + "\n"
+	_ = ioutil.WriteFile(fileName, []byte(strings.Repeat(line, 2048)), 0644)
+
+	helper := `{"filename":"./linestate/state.log", "rotate":true, "daily":false, "maxlines":100000}`
+	open := func(expected int) {
+		w, err := newFileAdapter(LevelTraceStr, helper)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if w.maxLinesCurLines != expected {
+			t.Error(w.maxLinesCurLines, "not "+strconv.Itoa(expected)+" lines")
+		}
+		w.Destroy()
+	}
+
+	open(2048)
+	stateName := lineStateName(fileName)
+	data, err := ioutil.ReadFile(stateName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the saved count must be trusted while the file not changed
+	var state lineState
+	_ = json.Unmarshal(data, &state)
+	state.Lines = 7
+	data, _ = json.Marshal(state)
+	_ = ioutil.WriteFile(stateName, data, 0644)
+	open(7)
+
+	// file changed, count again
+	f, _ := os.OpenFile(fileName, os.O_WRONLY|os.O_APPEND, 0)
+	_, _ = f.WriteString("new line\n")
+	_ = f.Close()
+	open(2049)
+
+	// destroy again, like Close by finalizer, must keep the state
+	w, err := newFileAdapter(LevelTraceStr, helper)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Destroy()
+	w.Destroy()
+	if _, err = os.Stat(stateName); err != nil {
+		t.Error("state removed by the second destroy:", err)
+	}
+
+	_ = os.RemoveAll("./linestate/")
+}
+
+func TestLineStateSkipWithoutMaxLines(t *testing.T) {
+	_ = os.MkdirAll("./linestate-skip/", 0755)
+	_ = ioutil.WriteFile("./linestate-skip/skip.log", []byte("a\nb\nc\n"), 0644)
+
+	w, err := newFileAdapter(LevelTraceStr, `{"filename":"./linestate-skip/skip.log", "rotate":false}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.maxLinesCurLines != 0 {
+		t.Error("should not count lines when not rotate by lines")
+	}
+	w.Destroy()
+
+	files, _ := ioutil.ReadDir("./linestate-skip/")
+	expected := 1
+	if len(files) != expected {
+		t.Error(len(files), "not "+strconv.Itoa(expected)+" file")
+	}
+	_ = os.RemoveAll("./linestate-skip/")
+}
+
+func TestRecoverRotate(t *testing.T) {
+	_ = os.MkdirAll("./recover/", 0755)
+	journal := "./recover/.recover.log.rotate"
+
+	// died before rename
+	_ = ioutil.WriteFile("./recover/recover.log", []byte("a\nb\n"), 0644)
+	_ = ioutil.WriteFile(journal,
+		[]byte(`{"old":"./recover/recover.log","new":"./recover/recover-2000-01-01-1.log","reason":"size"}`), 0644)
+
+	log := NewLogger()
+	if err := log.AddAdapter("file", "trace", `{"filename":"./recover/recover.log", "rotate":false}`); err != nil {
+		t.Fatal(err)
+	}
+	log.Close()
+
+	if f, err := os.Stat("./recover/recover-2000-01-01-1.log"); err != nil {
+		t.Error("rotation not completed:", err)
+	} else if f.Mode().Perm() != 0444 {
+		t.Error(f.Mode().Perm(), "not", os.FileMode(0444))
+	}
+	if _, err := os.Stat(journal); !os.IsNotExist(err) {
+		t.Error("journal not removed")
+	}
+
+	// died after rename
+	_ = ioutil.WriteFile("./recover/recover-2000-01-01-2.log", []byte("a\n"), 0644)
+	_ = os.Remove("./recover/recover.log")
+	_ = ioutil.WriteFile(journal,
+		[]byte(`{"old":"./recover/recover.log","new":"./recover/recover-2000-01-01-2.log","reason":"size"}`), 0644)
+
+	log = NewLogger()
+	if err := log.AddAdapter("file", "trace", `{"filename":"./recover/recover.log", "rotate":false}`); err != nil {
+		t.Fatal(err)
+	}
+	log.Close()
+
+	if f, err := os.Stat("./recover/recover-2000-01-01-2.log"); err != nil || f.Mode().Perm() != 0444 {
+		t.Error("rotation not completed:", err)
+	}
+	if _, err := os.Stat(journal); !os.IsNotExist(err) {
+		t.Error("journal not removed")
+	}
+
+	_ = os.RemoveAll("./recover/")
+}
+
+func TestRotateError(t *testing.T) {
+	// journal can not be written when a directory take its name
+	_ = os.MkdirAll("./rotate-error/.error.log.rotate/", 0755)
+	_ = ioutil.WriteFile("./rotate-error/.error.log.rotate/keep", nil, 0644)
+
+	errs := make(chan error, 8)
+	log := NewLogger()
+	log.OnError(func(err error) {
+		errs <- err
+	})
+	if err := log.AddAdapter("file", "trace", `{"filename":"./rotate-error/error.log", "rotate":false}`); err != nil {
+		t.Fatal(err)
+	}
+
+	testFileCalls(log)
+	if _, ok := log.Rotate().(*RotateError); !ok {
+		t.Error("not get RotateError")
+	}
+	testFileCalls(log)
+	log.Close()
+
+	got := false
+	for !got {
+		select {
+		case err := <-errs:
+			_, got = err.(*RotateError)
+		case <-time.After(time.Second):
+			t.Fatal("no RotateError reported")
+		}
+	}
+
+	f, _ := os.Open("./rotate-error/error.log")
+	b := bufio.NewReader(f)
+	lineNum := 0
+	for {
+		if _, _, err := b.ReadLine(); err != nil {
+			break
+		}
+		lineNum++
+	}
+	_ = f.Close()
+	if expected := 2 * LevelError; lineNum != expected {
+		t.Error(lineNum, "not "+strconv.Itoa(expected)+" lines")
+	}
+
+	_ = os.RemoveAll("./rotate-error/")
+}
@@ -0,0 +1,193 @@
+package logs
+
+import (
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// fingersCrossedWriter buffer records below trigger level and drop them,
+// until a record at or above trigger level come, then write the buffered
+// records and it to the wrapped adapter
+type fingersCrossedWriter struct {
+	sync.Mutex
+
+	level  int
+	writer logWriter
+
+	// Wrapped adapter and its helper, it has the same level as this adapter
+	Adapter string          `json:"adapter"`
+	Helper  json.RawMessage `json:"helper"`
+	// Level name to flush the buffer
+	Trigger string `json:"trigger"`
+	// Max records kept in one buffer, older ones are dropped
+	BufferSize int `json:"buffersize"`
+	// Field to separate buffers, like "request_id", empty means one buffer
+	Scope string `json:"scope"`
+	// Max buffers kept when Scope is set, least recently used one is dropped
+	MaxScopes int `json:"maxscopes"`
+
+	trigger int
+	buffers map[string]*list.Element
+	// least recently used buffer at front
+	lru *list.List
+}
+
+// fingersCrossedBuffer is the buffer of one scope
+type fingersCrossedBuffer struct {
+	scope    string
+	messages []logMessage
+}
+
+func (w *fingersCrossedWriter) WriteMsg(message logMessage) error {
+	if message.level < w.level {
+		return nil
+	}
+
+	scope := ""
+	if w.Scope != "" {
+		if v, ok := message.fields[w.Scope]; ok {
+			scope = fmt.Sprintf("%+v", v)
+		}
+	}
+
+	w.Lock()
+	if message.level < w.trigger {
+		w.buffer(scope, message)
+		w.Unlock()
+		return nil
+	}
+	messages := w.take(scope)
+	w.Unlock()
+
+	for _, m := range messages {
+		if err := w.writer.WriteMsg(m); err != nil {
+			return err
+		}
+	}
+	return w.writer.WriteMsg(message)
+}
+
+// buffer keep message in buffer of scope, must hold lock
+func (w *fingersCrossedWriter) buffer(scope string, message logMessage) {
+	var b *fingersCrossedBuffer
+	if e, ok := w.buffers[scope]; ok {
+		w.lru.MoveToBack(e)
+		b = e.Value.(*fingersCrossedBuffer)
+	} else {
+		if w.lru.Len() >= w.MaxScopes {
+			oldest := w.lru.Remove(w.lru.Front()).(*fingersCrossedBuffer)
+			delete(w.buffers, oldest.scope)
+		}
+		b = &fingersCrossedBuffer{scope: scope}
+		w.buffers[scope] = w.lru.PushBack(b)
+	}
+
+	if len(b.messages) >= w.BufferSize {
+		copy(b.messages, b.messages[1:])
+		b.messages = b.messages[:len(b.messages)-1]
+	}
+	b.messages = append(b.messages, message)
+}
+
+// take remove buffer of scope and return its messages, must hold lock
+func (w *fingersCrossedWriter) take(scope string) []logMessage {
+	e, ok := w.buffers[scope]
+	if !ok {
+		return nil
+	}
+	delete(w.buffers, scope)
+	return w.lru.Remove(e).(*fingersCrossedBuffer).messages
+}
+
+func (w *fingersCrossedWriter) Flush() {
+	w.writer.Flush()
+}
+
+// Destroy drop buffered records, they are never triggered
+func (w *fingersCrossedWriter) Destroy() {
+	w.Lock()
+	w.buffers = make(map[string]*list.Element)
+	w.lru.Init()
+	w.Unlock()
+	w.writer.Destroy()
+}
+
+func (w *fingersCrossedWriter) unwrap() logWriter {
+	return w.writer
+}
+
+func (w *fingersCrossedWriter) setRotateHook(fn func(RotateEvent)) {
+	if rw, ok := w.writer.(rotateWriter); ok {
+		rw.setRotateHook(fn)
+	}
+}
+
+func (w *fingersCrossedWriter) forceRotate() error {
+	if rw, ok := w.writer.(rotateWriter); ok {
+		return rw.forceRotate()
+	}
+	return nil
+}
+
+func (w *fingersCrossedWriter) setErrorHook(fn func(error)) {
+	if ew, ok := w.writer.(errorWriter); ok {
+		ew.setErrorHook(fn)
+	}
+}
+
+func newFingersCrossedAdapter(level string, helper string) (writer *fingersCrossedWriter, err error) {
+	w := getFingersCrossedWriter()
+
+	if err = decodeHelper(AdapterFingersCrossed, helper, w); err != nil {
+		return
+	}
+
+	if w.level = getLevelInt(level); w.level == -1 {
+		err = NoSupportLevel
+		return
+	}
+
+	if err = w.validate(); err != nil {
+		return
+	}
+
+	if w.writer, err = newAdapter(w.Adapter, level, string(w.Helper)); err != nil {
+		if err == NoSupportAdapter {
+			err = newConfigError(AdapterFingersCrossed, "adapter", "unknown adapter %q", w.Adapter)
+		}
+		return
+	}
+
+	w.buffers = make(map[string]*list.Element)
+	w.lru = list.New()
+
+	writer = w
+	return
+}
+
+func (w *fingersCrossedWriter) validate() error {
+	if w.Adapter == "" {
+		return newConfigError(AdapterFingersCrossed, "adapter", "must not be empty")
+	}
+	if w.trigger = getLevelInt(w.Trigger); w.trigger == -1 {
+		return newConfigError(AdapterFingersCrossed, "trigger", "unknown level %q", w.Trigger)
+	}
+	if w.BufferSize <= 0 {
+		return newConfigError(AdapterFingersCrossed, "buffersize", "must be positive")
+	}
+	if w.MaxScopes <= 0 {
+		return newConfigError(AdapterFingersCrossed, "maxscopes", "must be positive")
+	}
+	return nil
+}
+
+func getFingersCrossedWriter() *fingersCrossedWriter {
+	return &fingersCrossedWriter{
+		Trigger:    LevelErrorStr,
+		BufferSize: 100,
+		MaxScopes:  1000,
+		level:      LevelInfo,
+	}
+}
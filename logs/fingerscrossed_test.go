@@ -0,0 +1,99 @@
+package logs
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func fingersCrossedMessages(log *Logger) string {
+	var messages []string
+	for _, record := range log.MemoryBuffer("crossed").Snapshot() {
+		messages = append(messages, record.Message)
+	}
+	return strings.Join(messages, ",")
+}
+
+func TestFingersCrossed(t *testing.T) {
+	log := NewLogger()
+	err := log.AddAdapter(AdapterFingersCrossed, LevelDebugStr, `{"adapter":"memory", "helper":{"name":"crossed"}, "buffersize":2}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	log.Debug("dropped")
+	log.Debug("debug")
+	log.Warning("warning")
+	if messages := fingersCrossedMessages(log); messages != "" {
+		t.Error("written before triggered:", messages)
+	}
+
+	log.Error("error")
+	log.Info("after")
+	if messages := fingersCrossedMessages(log); messages != "debug,warning,error" {
+		t.Error("wrong messages:", messages)
+	}
+	log.Close()
+}
+
+func TestFingersCrossedScope(t *testing.T) {
+	log := NewLogger()
+	err := log.AddAdapter(AdapterFingersCrossed, LevelInfoStr, `{"adapter":"memory", "helper":{"name":"crossed"},
+		"trigger":"warning", "scope":"request", "maxscopes":2}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a, b, c := log.WithFields(Fields{"request": "a"}), log.WithFields(Fields{"request": "b"}),
+		log.WithFields(Fields{"request": "c"})
+	a.Info("a1")
+	b.Info("b1")
+	a.Info("a2")
+	// b is the least recently used buffer
+	c.Info("c1")
+	b.Warning("b2")
+	a.Warning("a3")
+	if messages := fingersCrossedMessages(log); messages != "b2,a1,a2,a3" {
+		t.Error("wrong messages:", messages)
+	}
+	log.Close()
+}
+
+// wrapped adapter is found through fingerscrossed and ratelimit
+func TestFingersCrossedUnwrap(t *testing.T) {
+	server := httptest.NewServer(&httpCollector{})
+	defer server.Close()
+
+	log := NewLogger()
+	err := log.AddAdapter(AdapterFingersCrossed, LevelInfoStr, `{"adapter":"http", "helper":{"url":"`+server.URL+`"},
+		"ratelimit":{"rate":100}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log.Error("error")
+	log.Close()
+
+	if stats := log.DeliveryStats(); len(stats) != 1 || stats[0].Sent != 1 {
+		t.Error("wrong delivery stats:", stats)
+	}
+	if stats := log.Stats().Adapters[0]; stats.Delivery == nil || stats.RateLimit == nil || stats.RateLimit.Passed != 1 {
+		t.Error("wrong adapter stats:", stats)
+	}
+}
+
+func TestFingersCrossedInvalid(t *testing.T) {
+	log := NewLogger()
+	cases := map[string]string{
+		`{}`:                    "adapter",
+		`{"adapter":"mongodb"}`: "adapter",
+		`{"adapter":"memory", "trigger":"fatal"}`:      "trigger",
+		`{"adapter":"memory", "buffersize":0}`:         "buffersize",
+		`{"adapter":"memory", "helper":{"records":0}}`: "records",
+	}
+	for helper, field := range cases {
+		err := log.AddAdapter(AdapterFingersCrossed, LevelInfoStr, helper)
+		if cErr, ok := err.(*ConfigError); !ok || cErr.Field != field {
+			t.Error(helper, "not get ConfigError on", field, "but", err)
+		}
+	}
+}
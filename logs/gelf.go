@@ -0,0 +1,339 @@
+package logs
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	GELFCompressGzip = "gzip"
+	GELFCompressZlib = "zlib"
+	GELFCompressNone = "none"
+)
+
+const (
+	// magic bytes, message id, sequence number and count
+	gelfChunkHeaderSize = 12
+	gelfMaxChunks       = 128
+)
+
+var gelfChunkMagic = []byte{0x1e, 0x0f}
+
+type gelfWriter struct {
+	sync.Mutex
+
+	level int
+	conn  net.Conn
+	// closed when peer close the tcp conn
+	connClosed chan struct{}
+
+	// "udp", "tcp" or "tls"
+	Network string `json:"network"`
+	Address string `json:"address"`
+	// Host of every message, default is hostname
+	Host string `json:"host"`
+	// gzip, zlib or none, only for udp, default is gzip
+	Compress string `json:"compress"`
+	// Max size of udp datagram, larger message is chunked
+	ChunkSize ByteSize `json:"chunksize"`
+	Timeout   Duration `json:"timeout"`
+
+	// Reconnect delay, double after each failure until MaxBackoff
+	Backoff    Duration `json:"backoff"`
+	MaxBackoff Duration `json:"maxbackoff"`
+	retry      backoff
+
+	tlsOptions
+
+	tlsConfig *tls.Config
+}
+
+func (w *gelfWriter) WriteMsg(message logMessage) (err error) {
+	if message.level < w.level {
+		return nil
+	}
+
+	data, err := w.encode(message)
+	if err != nil {
+		return
+	}
+
+	w.Lock()
+	defer w.Unlock()
+
+	if w.conn != nil && isClosed(w.connClosed) {
+		w.closeConn()
+	}
+
+	// write once, if failed reconnect and write again
+	if w.conn != nil {
+		if err = w.write(data); err == nil {
+			return
+		}
+		w.closeConn()
+	}
+
+	if err = w.connect(); err != nil {
+		return
+	}
+	if err = w.write(data); err != nil {
+		w.closeConn()
+		w.retry.fail()
+	}
+	return
+}
+
+func (w *gelfWriter) Flush() {
+}
+
+func (w *gelfWriter) Destroy() {
+	w.Lock()
+	w.closeConn()
+	w.Unlock()
+}
+
+// connect dial to graylog, fail fast while the backoff of last failure not expired
+func (w *gelfWriter) connect() (err error) {
+	if !w.retry.ready() {
+		return fmt.Errorf("gelf adapter: %s is down, retry after %s", w.Address, w.retry.next.Format(time.RFC3339))
+	}
+
+	timeout := time.Duration(w.Timeout)
+	if w.Network == "tls" {
+		w.conn, err = dialConn("tcp", w.Address, timeout, w.tlsConfig)
+	} else {
+		w.conn, err = dialConn(w.Network, w.Address, timeout, nil)
+	}
+	if err != nil {
+		w.conn = nil
+		w.retry.fail()
+		return
+	}
+	w.retry.reset()
+
+	w.connClosed = nil
+	if w.Network != "udp" {
+		// graylog never send anything
+		w.connClosed = watchPeerClose(w.conn)
+	}
+	return
+}
+
+func (w *gelfWriter) closeConn() {
+	if w.conn != nil {
+		_ = w.conn.Close()
+		w.conn = nil
+	}
+}
+
+// write send message by null byte framing on tcp, or datagrams on udp
+func (w *gelfWriter) write(data []byte) (err error) {
+	if w.Timeout > 0 {
+		_ = w.conn.SetWriteDeadline(time.Now().Add(time.Duration(w.Timeout)))
+	}
+	if w.Network != "udp" {
+		_, err = w.conn.Write(append(data, 0))
+		return
+	}
+
+	for _, datagram := range w.chunks(data) {
+		if _, err = w.conn.Write(datagram); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// chunks split data into gelf chunks if it is larger than ChunkSize
+func (w *gelfWriter) chunks(data []byte) [][]byte {
+	size := int(w.ChunkSize)
+	if len(data) <= size {
+		return [][]byte{data}
+	}
+
+	payload := size - gelfChunkHeaderSize
+	count := (len(data) + payload - 1) / payload
+
+	id := make([]byte, 8)
+	_, _ = rand.Read(id)
+
+	chunks := make([][]byte, 0, count)
+	for i := 0; i < count; i++ {
+		end := (i + 1) * payload
+		if end > len(data) {
+			end = len(data)
+		}
+		chunk := make([]byte, 0, gelfChunkHeaderSize+end-i*payload)
+		chunk = append(chunk, gelfChunkMagic...)
+		chunk = append(chunk, id...)
+		chunk = append(chunk, byte(i), byte(count))
+		chunks = append(chunks, append(chunk, data[i*payload:end]...))
+	}
+	return chunks
+}
+
+// encode format message as gelf 1.1 json, compressed when using udp
+func (w *gelfWriter) encode(message logMessage) (data []byte, err error) {
+	record := map[string]interface{}{
+		"version":       "1.1",
+		"host":          w.Host,
+		"short_message": message.message,
+		"timestamp":     float64(message.time.UnixNano()/int64(time.Millisecond)) / 1000,
+		"level":         syslogSeverity[message.level],
+	}
+	if i := strings.IndexByte(message.message, '\n'); i >= 0 {
+		record["short_message"] = message.message[:i]
+		record["full_message"] = message.message
+	}
+	if message.trace.file != "" {
+		record["_file"] = message.trace.file
+		record["_line"] = message.trace.line
+		record["_func"] = message.trace.funcName
+	}
+	for k, v := range message.fields {
+		record[gelfFieldName(k)] = gelfFieldValue(v)
+	}
+
+	if data, err = json.Marshal(record); err != nil {
+		return
+	}
+
+	switch w.Compress {
+	case GELFCompressGzip:
+		data, err = gzipBytes(data)
+	case GELFCompressZlib:
+		data, err = zlibBytes(data)
+	}
+	if err == nil && w.Network == "udp" && len(data) > gelfMaxChunks*(int(w.ChunkSize)-gelfChunkHeaderSize) {
+		err = fmt.Errorf("gelf adapter: message of %d bytes need more than %d chunks", len(data), gelfMaxChunks)
+	}
+	return
+}
+
+// gelfFieldName return additional field name of record field,
+// chars not allowed are replaced by '_', and reserved "_id" becomes "_id_"
+func gelfFieldName(name string) string {
+	name = "_" + strings.Map(func(r rune) rune {
+		if r == '_' || r == '.' || r == '-' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, name)
+	if name == "_id" {
+		name = "_id_"
+	}
+	return name
+}
+
+// gelfFieldValue keep numbers, use string for others as gelf only accept string and number
+func gelfFieldValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, string:
+		return v
+	case error:
+		return v.Error()
+	}
+	return fmt.Sprintf("%+v", value)
+}
+
+// zlibBytes compress data with zlib
+func zlibBytes(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func newGELFAdapter(level string, helper string) (writer *gelfWriter, err error) {
+	w := getGELFWriter()
+
+	if err = decodeHelper(AdapterGELF, helper, w); err != nil {
+		return
+	}
+
+	if w.level = getLevelInt(level); w.level == -1 {
+		err = NoSupportLevel
+		return
+	}
+
+	if err = w.validate(); err != nil {
+		return
+	}
+
+	w.retry = backoff{initial: time.Duration(w.Backoff), max: time.Duration(w.MaxBackoff)}
+	// graylog can be down at start, records fail until it is up
+	_ = w.connect()
+
+	writer = w
+	return
+}
+
+func (w *gelfWriter) validate() (err error) {
+	switch w.Network {
+	case "udp", "tcp", "tls":
+	default:
+		return newConfigError(AdapterGELF, "network", "unknown network %q", w.Network)
+	}
+	if w.Address == "" {
+		return newConfigError(AdapterGELF, "address", "must not be empty")
+	}
+
+	switch w.Compress {
+	case "":
+		w.Compress = GELFCompressNone
+		if w.Network == "udp" {
+			w.Compress = GELFCompressGzip
+		}
+	case GELFCompressGzip, GELFCompressZlib:
+		if w.Network != "udp" {
+			return newConfigError(AdapterGELF, "compress", "only support udp")
+		}
+	case GELFCompressNone:
+	default:
+		return newConfigError(AdapterGELF, "compress", "unknown compress %q", w.Compress)
+	}
+
+	if w.ChunkSize <= gelfChunkHeaderSize {
+		return newConfigError(AdapterGELF, "chunksize", "must be larger than %d", gelfChunkHeaderSize)
+	}
+
+	if w.Backoff <= 0 {
+		return newConfigError(AdapterGELF, "backoff", "must be positive")
+	}
+	if w.MaxBackoff < w.Backoff {
+		return newConfigError(AdapterGELF, "maxbackoff", "must not be less than backoff")
+	}
+
+	if w.Network == "tls" {
+		w.tlsConfig, err = w.tlsOptions.tlsConfig(AdapterGELF)
+	}
+	return
+}
+
+func getGELFWriter() *gelfWriter {
+	hostname, _ := os.Hostname()
+	return &gelfWriter{
+		Network: "udp",
+		Host:    hostname,
+		// fit in the ethernet mtu
+		ChunkSize:  1420,
+		Timeout:    Duration(5 * time.Second),
+		Backoff:    Duration(time.Second),
+		MaxBackoff: Duration(time.Minute),
+		level:      LevelInfo,
+	}
+}
@@ -0,0 +1,182 @@
+package logs
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readGELF read one udp message, reassemble chunks and decompress it
+func readGELF(t *testing.T, conn net.PacketConn) map[string]interface{} {
+	chunks := make(map[byte][]byte)
+	buf := make([]byte, 65536)
+	for {
+		_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatal("no message received:", err)
+		}
+		data := append([]byte(nil), buf[:n]...)
+		if !bytes.HasPrefix(data, gelfChunkMagic) {
+			return decodeGELF(t, data)
+		}
+
+		chunks[data[10]] = data[gelfChunkHeaderSize:]
+		if count := int(data[11]); len(chunks) == count {
+			var message []byte
+			for i := 0; i < count; i++ {
+				message = append(message, chunks[byte(i)]...)
+			}
+			return decodeGELF(t, message)
+		}
+	}
+}
+
+func decodeGELF(t *testing.T, data []byte) (record map[string]interface{}) {
+	var r io.Reader = bytes.NewReader(data)
+	var err error
+	switch {
+	case bytes.HasPrefix(data, []byte{0x1f, 0x8b}):
+		r, err = gzip.NewReader(r)
+	case data[0] == 0x78:
+		r, err = zlib.NewReader(r)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ = ioutil.ReadAll(r)
+	if err = json.Unmarshal(data, &record); err != nil {
+		t.Fatal(err, string(data))
+	}
+	return
+}
+
+func TestGELFUDPChunk(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	log := NewLogger()
+	err = log.AddAdapter(AdapterGELF, LevelInfoStr, `{"address":"`+conn.LocalAddr().String()+
+		`", "host":"box", "compress":"zlib", "chunksize":64}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log.WithFields(Fields{"user id": 7, "id": "x"}).Error("denied\nstack")
+	log.Close()
+
+	record := readGELF(t, conn)
+	if record["version"] != "1.1" || record["host"] != "box" || record["level"] != float64(3) {
+		t.Error("wrong header:", record)
+	}
+	if record["short_message"] != "denied" || record["full_message"] != "denied\nstack" {
+		t.Error("wrong message:", record)
+	}
+	if record["_user_id"] != float64(7) || record["_id_"] != "x" {
+		t.Error("wrong fields:", record)
+	}
+	if file, _ := record["_file"].(string); !strings.HasSuffix(file, "gelf_test.go") || record["_line"] == nil {
+		t.Error("wrong caller:", record)
+	}
+}
+
+func TestGELFTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	received := make(chan string, 8)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for {
+			frame, err := r.ReadString(0)
+			if err != nil {
+				return
+			}
+			received <- strings.TrimSuffix(frame, "\x00")
+		}
+	}()
+
+	log := NewLogger()
+	if err = log.AddAdapter(AdapterGELF, LevelInfoStr, `{"network":"tcp", "address":"`+ln.Addr().String()+`"}`); err != nil {
+		t.Fatal(err)
+	}
+	testFileCalls(log)
+	log.Close()
+
+	for _, expected := range []string{"error", "warning", "info"} {
+		record := decodeGELF(t, []byte(receiveLine(t, received)))
+		if record["short_message"] != expected {
+			t.Error("wrong message:", record)
+		}
+	}
+}
+
+func TestGELFBackoff(t *testing.T) {
+	// a closed port, nothing listen on it
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := ln.Addr().String()
+	_ = ln.Close()
+
+	w, err := newGELFAdapter(LevelInfoStr, `{"network":"tcp", "address":"`+address+`", "backoff":"1h", "maxbackoff":"1h"}`)
+	if err != nil {
+		t.Fatal("graylog down at start should not fail:", err)
+	}
+	defer w.Destroy()
+
+	message := logMessage{level: LevelInfo, message: "down", time: time.Now()}
+	if err = w.WriteMsg(message); err == nil || !strings.Contains(err.Error(), "retry after") {
+		t.Error("not fail fast in backoff:", err)
+	}
+
+	ln, err = net.Listen("tcp", address)
+	if err != nil {
+		t.Skip("can not listen again:", err)
+	}
+	defer ln.Close()
+	if err = w.WriteMsg(message); err == nil {
+		t.Error("dial in backoff")
+	}
+	w.retry.reset()
+	if err = w.WriteMsg(message); err != nil {
+		t.Error("not reconnect after backoff:", err)
+	}
+}
+
+func TestGELFInvalid(t *testing.T) {
+	log := NewLogger()
+	cases := map[string]string{
+		`{"address":""}`: "address",
+		`{"network":"unix", "address":"/dev/log"}`:                 "network",
+		`{"network":"tcp", "address":":12201", "compress":"gzip"}`: "compress",
+		`{"address":":12201", "compress":"lz4"}`:                   "compress",
+		`{"address":":12201", "chunksize":12}`:                     "chunksize",
+		`{"address":":12201", "backoff":"0s"}`:                     "backoff",
+	}
+	for helper, field := range cases {
+		err := log.AddAdapter(AdapterGELF, LevelInfoStr, helper)
+		if cErr, ok := err.(*ConfigError); !ok || cErr.Field != field {
+			t.Error(helper, "not get ConfigError on", field, "but", err)
+		}
+	}
+}
@@ -0,0 +1,308 @@
+package logs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// one json record a line
+	HTTPFormatNDJSON = "ndjson"
+	// all records in one json array
+	HTTPFormatJSON = "json"
+)
+
+// HTTPStatusError is returned when server not response 2xx
+type HTTPStatusError struct {
+	URL        string
+	StatusCode int
+	Status     string
+	// From Retry-After header, zero if not set
+	RetryAfter time.Duration
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("%s responded %s", e.URL, e.Status)
+}
+
+// retryable report whether the request may success later
+func (e *HTTPStatusError) retryable() bool {
+	return e.StatusCode >= 500 || e.StatusCode == http.StatusTooManyRequests
+}
+
+// doHTTP send req, return body of 2xx response or *HTTPStatusError.
+// Network error and timeout are retryable
+func doHTTP(client *http.Client, req *http.Request) (body []byte, retryable bool, err error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, true, err
+	}
+	// read body to reuse the conn
+	body, err = ioutil.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		statusErr := &HTTPStatusError{URL: req.URL.String(), StatusCode: resp.StatusCode, Status: resp.Status}
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+			statusErr.RetryAfter = time.Duration(seconds) * time.Second
+		}
+		return nil, statusErr.retryable(), statusErr
+	}
+	return body, err != nil, err
+}
+
+// setHTTPHeaders set user headers to req, they can override default headers
+func setHTTPHeaders(req *http.Request, headers map[string]string) {
+	for k, v := range headers {
+		if strings.EqualFold(k, "Host") {
+			req.Host = v
+		} else {
+			req.Header.Set(k, v)
+		}
+	}
+}
+
+func newHTTPClient(tlsConfig *tls.Config, timeout time.Duration) *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{Proxy: http.ProxyFromEnvironment, TLSClientConfig: tlsConfig},
+		Timeout:   timeout,
+	}
+}
+
+// gzipBytes compress data with gzip
+func gzipBytes(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+type httpWriter struct {
+	// first field to make sure 64-bit aligned
+	counter deliveryCounter
+
+	level int
+
+	URL     string            `json:"url"`
+	Method  string            `json:"method"`
+	Format  string            `json:"format"`
+	Headers map[string]string `json:"headers"`
+	Gzip    bool              `json:"gzip"`
+	Timeout Duration          `json:"timeout"`
+	tlsOptions
+
+	// Retry on 5xx, 429 and network error
+	batchOptions
+
+	// Batches still failed after retries are kept in spool file, empty means drop them
+	Spool        string   `json:"spool"`
+	SpoolMaxSize ByteSize `json:"spoolmaxsize"`
+	spool        *spool
+	// delay replay of spool after a failed delivery, grow as retry backoff
+	down backoff
+
+	client  *http.Client
+	batcher *batcher
+	errorReporter
+}
+
+func (w *httpWriter) WriteMsg(message logMessage) error {
+	if message.level < w.level {
+		return nil
+	}
+
+	err := w.batcher.add(batchRecord{message: message, data: jsonMessage(w.level, message)})
+	if err != nil {
+		atomic.AddUint64(&w.counter.dropped, 1)
+	}
+	return err
+}
+
+func (w *httpWriter) Flush() {
+	w.batcher.flush()
+}
+
+func (w *httpWriter) Destroy() {
+	w.batcher.close()
+	if w.spool != nil {
+		w.spool.destroy()
+	}
+}
+
+func (w *httpWriter) reportError(err error) {
+	w.counter.setError(err)
+	w.errorReporter.reportError(err)
+}
+
+func (w *httpWriter) deliveryStats() DeliveryStats {
+	return w.counter.stats(AdapterHTTP)
+}
+
+// send is called by batcher in its goroutine
+func (w *httpWriter) send(batch []batchRecord) {
+	records := make([]string, len(batch))
+	for i, record := range batch {
+		records[i] = string(record.data)
+	}
+
+	// spooled records must be sent first to keep order
+	err := w.replaySpool()
+	if err == nil {
+		if err = w.post(records); err == nil {
+			w.down.reset()
+			return
+		}
+	}
+
+	w.down.fail()
+	w.reportError(err)
+	if w.spool == nil {
+		atomic.AddUint64(&w.counter.failed, uint64(len(records)))
+		return
+	}
+	for _, record := range records {
+		if w.spool.add(record) != nil {
+			atomic.AddUint64(&w.counter.failed, 1)
+		} else {
+			atomic.AddUint64(&w.counter.spooled, 1)
+		}
+	}
+}
+
+// replaySpool send spooled records, fail fast while the endpoint is known down,
+// so every batch not wait for retries of the spooled ones
+func (w *httpWriter) replaySpool() error {
+	if w.spool == nil || !w.spool.pending() {
+		return nil
+	}
+	if !w.down.ready() {
+		return fmt.Errorf("http adapter: %s is down, retry after %s", w.URL, w.down.next.Format(time.RFC3339))
+	}
+	return w.spool.replay(w.BatchSize, w.post)
+}
+
+// post send records in one request, retry if the error is temporary
+func (w *httpWriter) post(records []string) (err error) {
+	body, err := w.encode(records)
+	if err != nil {
+		return
+	}
+
+	err = w.retry(&w.counter, func() (bool, error) {
+		return w.postOnce(body)
+	})
+	if err == nil {
+		atomic.AddUint64(&w.counter.sent, uint64(len(records)))
+		atomic.AddUint64(&w.counter.batches, 1)
+	}
+	return
+}
+
+func (w *httpWriter) postOnce(body []byte) (retryable bool, err error) {
+	req, err := http.NewRequest(w.Method, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+
+	if w.Format == HTTPFormatNDJSON {
+		req.Header.Set("Content-Type", "application/x-ndjson")
+	} else {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if w.Gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	setHTTPHeaders(req, w.Headers)
+
+	_, retryable, err = doHTTP(w.client, req)
+	return
+}
+
+func (w *httpWriter) encode(records []string) ([]byte, error) {
+	var body []byte
+	if w.Format == HTTPFormatNDJSON {
+		body = []byte(strings.Join(records, "\n") + "\n")
+	} else {
+		body = []byte("[" + strings.Join(records, ",") + "]")
+	}
+	if !w.Gzip {
+		return body, nil
+	}
+
+	return gzipBytes(body)
+}
+
+func newHTTPAdapter(level string, helper string) (writer *httpWriter, err error) {
+	w := getHTTPWriter()
+
+	if err = decodeHelper(AdapterHTTP, helper, w); err != nil {
+		return
+	}
+
+	if w.level = getLevelInt(level); w.level == -1 {
+		err = NoSupportLevel
+		return
+	}
+
+	if err = w.validate(); err != nil {
+		return
+	}
+
+	tlsConfig, err := w.tlsOptions.tlsConfig(AdapterHTTP)
+	if err != nil {
+		return
+	}
+	w.client = newHTTPClient(tlsConfig, time.Duration(w.Timeout))
+
+	if w.Spool != "" {
+		if w.spool, err = newSpool(w.Spool, w.SpoolMaxSize); err != nil {
+			return
+		}
+	}
+
+	w.down = backoff{initial: time.Duration(w.Backoff), max: time.Duration(w.MaxBackoff)}
+	w.batcher = w.newBatcher(w.send)
+
+	writer = w
+	return
+}
+
+func (w *httpWriter) validate() error {
+	u, err := url.Parse(w.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return newConfigError(AdapterHTTP, "url", "%q is not a http or https url", w.URL)
+	}
+	if w.Method == "" {
+		return newConfigError(AdapterHTTP, "method", "must not be empty")
+	}
+	switch w.Format {
+	case HTTPFormatNDJSON, HTTPFormatJSON:
+	default:
+		return newConfigError(AdapterHTTP, "format", "unknown format %q", w.Format)
+	}
+	return w.batchOptions.validate(AdapterHTTP)
+}
+
+func getHTTPWriter() *httpWriter {
+	return &httpWriter{
+		Method:       http.MethodPost,
+		Format:       HTTPFormatNDJSON,
+		Timeout:      Duration(10 * time.Second),
+		batchOptions: defaultBatchOptions(),
+		level:        LevelInfo,
+	}
+}
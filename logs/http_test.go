@@ -0,0 +1,253 @@
+package logs
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// httpCollector record every request body as lines of json
+type httpCollector struct {
+	sync.Mutex
+	bodies  [][]string
+	headers []http.Header
+	// status to response, empty means 200
+	status []int
+}
+
+func (c *httpCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.Lock()
+	defer c.Unlock()
+
+	status := http.StatusOK
+	if len(c.status) > 0 {
+		status, c.status = c.status[0], c.status[1:]
+	}
+	if status != http.StatusOK {
+		w.WriteHeader(status)
+		return
+	}
+
+	var body io.Reader = r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		body = zr
+	}
+
+	var lines []string
+	if r.Header.Get("Content-Type") == "application/json" {
+		var records []json.RawMessage
+		_ = json.NewDecoder(body).Decode(&records)
+		for _, record := range records {
+			lines = append(lines, string(record))
+		}
+	} else {
+		s := bufio.NewScanner(body)
+		for s.Scan() {
+			lines = append(lines, s.Text())
+		}
+	}
+	c.bodies = append(c.bodies, lines)
+	c.headers = append(c.headers, r.Header)
+}
+
+func (c *httpCollector) messages() (messages []string) {
+	c.Lock()
+	defer c.Unlock()
+	for _, lines := range c.bodies {
+		for _, line := range lines {
+			var record jsonRecord
+			_ = json.Unmarshal([]byte(line), &record)
+			messages = append(messages, record.Message)
+		}
+	}
+	return
+}
+
+func TestHTTPBatch(t *testing.T) {
+	collector := &httpCollector{}
+	server := httptest.NewServer(collector)
+	defer server.Close()
+
+	log := NewLogger()
+	err := log.AddAdapter(AdapterHTTP, LevelInfoStr, `{"url":"`+server.URL+
+		`", "batchsize":2, "flushinterval":"1h", "headers":{"X-Token":"secret"}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testFileCalls(log)
+	log.Close()
+
+	if len(collector.bodies) != 2 || len(collector.bodies[0]) != 2 || len(collector.bodies[1]) != 1 {
+		t.Error("wrong batches:", collector.bodies)
+	}
+	if messages := strings.Join(collector.messages(), ","); messages != "error,warning,info" {
+		t.Error("wrong messages:", messages)
+	}
+	if collector.headers[0].Get("X-Token") != "secret" {
+		t.Error("header not set")
+	}
+}
+
+func TestHTTPGzipJSONArray(t *testing.T) {
+	collector := &httpCollector{}
+	server := httptest.NewServer(collector)
+	defer server.Close()
+
+	log := NewLogger()
+	err := log.AddAdapter(AdapterHTTP, LevelInfoStr, `{"url":"`+server.URL+`", "format":"json", "gzip":true}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log.WithFields(Fields{"user": "box"}).Info("login")
+	log.Close()
+
+	if len(collector.bodies) != 1 || len(collector.bodies[0]) != 1 {
+		t.Fatal("wrong batches:", collector.bodies)
+	}
+	var record jsonRecord
+	_ = json.Unmarshal([]byte(collector.bodies[0][0]), &record)
+	if record.Message != "login" || record.Level != LevelInfoStr || record.Fields["user"] != "box" {
+		t.Error("wrong record:", collector.bodies[0][0])
+	}
+}
+
+func TestHTTPRetry(t *testing.T) {
+	collector := &httpCollector{status: []int{http.StatusServiceUnavailable, http.StatusBadGateway}}
+	server := httptest.NewServer(collector)
+	defer server.Close()
+
+	log := NewLogger()
+	err := log.AddAdapter(AdapterHTTP, LevelInfoStr, `{"url":"`+server.URL+`", "backoff":"1ms"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log.Info("retry")
+	log.Close()
+
+	stats := log.DeliveryStats()
+	if len(stats) != 1 || stats[0].Sent != 1 || stats[0].Retries != 2 || stats[0].Failed != 0 {
+		t.Error("wrong stats:", stats)
+	}
+}
+
+func TestHTTPNoRetryClientError(t *testing.T) {
+	collector := &httpCollector{status: []int{http.StatusBadRequest}}
+	server := httptest.NewServer(collector)
+	defer server.Close()
+
+	log := NewLogger()
+	errs := make(chan error, 8)
+	log.OnError(func(err error) {
+		errs <- err
+	})
+	err := log.AddAdapter(AdapterHTTP, LevelInfoStr, `{"url":"`+server.URL+`", "backoff":"1ms"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log.Info("bad")
+	log.Close()
+
+	stats := log.DeliveryStats()
+	if len(stats) != 1 || stats[0].Failed != 1 || stats[0].Retries != 0 {
+		t.Error("wrong stats:", stats)
+	}
+	if e, ok := (<-errs).(*HTTPStatusError); !ok || e.StatusCode != http.StatusBadRequest {
+		t.Error("not get HTTPStatusError")
+	}
+}
+
+func TestHTTPSpool(t *testing.T) {
+	collector := &httpCollector{status: []int{500, 500, 500}}
+	server := httptest.NewServer(collector)
+	defer server.Close()
+
+	log := NewLogger()
+	log.OnError(func(error) {})
+	err := log.AddAdapter(AdapterHTTP, LevelInfoStr, `{"url":"`+server.URL+
+		`", "retries":2, "backoff":"1ms", "flushinterval":"10ms", "spool":"./http-spool/http.spool"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	log.Info("first")
+	for i := 0; log.DeliveryStats()[0].Spooled != 1; i++ {
+		if i > 100 {
+			t.Fatal("record not spooled")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	log.Info("second")
+	log.Close()
+
+	if messages := strings.Join(collector.messages(), ","); messages != "first,second" {
+		t.Error("wrong messages:", messages)
+	}
+	if f, err := os.Stat("./http-spool/http.spool"); err != nil || f.Size() != 0 {
+		t.Error("spool not cleaned")
+	}
+	_ = os.RemoveAll("./http-spool/")
+}
+
+func TestHTTPSpoolWhileDown(t *testing.T) {
+	collector := &httpCollector{status: []int{500, 500}}
+	server := httptest.NewServer(collector)
+	defer server.Close()
+
+	log := NewLogger()
+	log.OnError(func(error) {})
+	err := log.AddAdapter(AdapterHTTP, LevelInfoStr, `{"url":"`+server.URL+
+		`", "retries":0, "backoff":"1h", "maxbackoff":"1h", "flushinterval":"10ms", "spool":"./http-spool-down/http.spool"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		log.Info("spooled {}", i)
+		for j := 0; log.DeliveryStats()[0].Spooled != uint64(i+1); j++ {
+			if j > 100 {
+				t.Fatal("record not spooled")
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+	log.Close()
+
+	// the second batch is spooled without a request, as the endpoint is down
+	collector.Lock()
+	if len(collector.status) != 1 {
+		t.Error("spool replayed while endpoint down")
+	}
+	collector.Unlock()
+	_ = os.RemoveAll("./http-spool-down/")
+}
+
+func TestHTTPInvalid(t *testing.T) {
+	log := NewLogger()
+	cases := map[string]string{
+		`{"url":"ftp://example.com"}`:                        "url",
+		`{"url":"http://example.com", "format":"xml"}`:       "format",
+		`{"url":"http://example.com", "batchsize":0}`:        "batchsize",
+		`{"url":"http://example.com", "retries":-1}`:         "retries",
+		`{"url":"http://example.com", "flushinterval":"0s"}`: "flushinterval",
+	}
+	for helper, field := range cases {
+		err := log.AddAdapter(AdapterHTTP, LevelInfoStr, helper)
+		if cErr, ok := err.(*ConfigError); !ok || cErr.Field != field {
+			t.Error(helper, "not get ConfigError on", field, "but", err)
+		}
+	}
+}
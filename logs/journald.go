@@ -0,0 +1,185 @@
+package logs
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const journaldSocket = "/run/systemd/journal/socket"
+
+type journaldWriter struct {
+	sync.Mutex
+
+	level int
+	conn  *net.UnixConn
+
+	// Native protocol socket of systemd-journald
+	Socket string `json:"socket"`
+	// SYSLOG_IDENTIFIER of every record, default is program name
+	Identifier string `json:"identifier"`
+}
+
+func (w *journaldWriter) WriteMsg(message logMessage) (err error) {
+	if message.level < w.level {
+		return nil
+	}
+
+	data := w.encode(message)
+
+	w.Lock()
+	defer w.Unlock()
+
+	// write once, if failed reconnect and write again, journald may be restarted
+	if w.conn != nil {
+		if err = w.write(data); err == nil {
+			return
+		}
+		w.closeConn()
+	}
+
+	if err = w.connect(); err != nil {
+		return
+	}
+	if err = w.write(data); err != nil {
+		w.closeConn()
+	}
+	return
+}
+
+func (w *journaldWriter) Flush() {
+}
+
+func (w *journaldWriter) Destroy() {
+	w.Lock()
+	w.closeConn()
+	w.Unlock()
+}
+
+func (w *journaldWriter) connect() (err error) {
+	w.conn, err = net.DialUnix("unixgram", nil, &net.UnixAddr{Name: w.Socket, Net: "unixgram"})
+	if err != nil {
+		w.conn = nil
+	}
+	return
+}
+
+func (w *journaldWriter) closeConn() {
+	if w.conn != nil {
+		_ = w.conn.Close()
+		w.conn = nil
+	}
+}
+
+// write send data in one datagram, if it is too large, send it by a file descriptor
+func (w *journaldWriter) write(data []byte) error {
+	_, err := w.conn.Write(data)
+	if err == nil || !isMessageTooLarge(err) {
+		return err
+	}
+	return journaldSendFile(w.conn, data)
+}
+
+// encode format message in journal native protocol
+func (w *journaldWriter) encode(message logMessage) []byte {
+	var data []byte
+	data = journaldField(data, "MESSAGE", message.message)
+	data = journaldField(data, "PRIORITY", strconv.Itoa(syslogSeverity[message.level]))
+	data = journaldField(data, "SYSLOG_IDENTIFIER", w.Identifier)
+	if message.trace.file != "" {
+		data = journaldField(data, "CODE_FILE", message.trace.file)
+		data = journaldField(data, "CODE_LINE", strconv.Itoa(message.trace.line))
+		data = journaldField(data, "CODE_FUNC", message.trace.funcName)
+	}
+	for _, k := range sortedFieldKeys(message.fields) {
+		name := journaldFieldName(k)
+		if name == "" {
+			continue
+		}
+		v := message.fields[k]
+		if err, ok := v.(error); ok {
+			v = err.Error()
+		}
+		data = journaldField(data, name, fmt.Sprintf("%+v", v))
+	}
+	return data
+}
+
+// journaldField append one field, value with newline is prefixed by its 64-bit little endian length
+func journaldField(data []byte, name string, value string) []byte {
+	if !strings.ContainsRune(value, '\n') {
+		return append(data, name+"="+value+"\n"...)
+	}
+
+	data = append(data, name+"\n"...)
+	size := make([]byte, 8)
+	binary.LittleEndian.PutUint64(size, uint64(len(value)))
+	data = append(data, size...)
+	return append(data, value+"\n"...)
+}
+
+// journaldFieldName upper case name and replace chars not allowed by '_',
+// leading '_' and digits are removed as they are for trusted fields
+func journaldFieldName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' {
+			return r - 'a' + 'A'
+		}
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, name)
+	name = strings.TrimLeft(name, "_0123456789")
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return name
+}
+
+func newJournaldAdapter(level string, helper string) (writer *journaldWriter, err error) {
+	w := getJournaldWriter()
+
+	if err = decodeHelper(AdapterJournald, helper, w); err != nil {
+		return
+	}
+
+	if w.level = getLevelInt(level); w.level == -1 {
+		err = NoSupportLevel
+		return
+	}
+
+	if err = w.validate(); err != nil {
+		return
+	}
+
+	if err = w.connect(); err != nil {
+		return
+	}
+
+	writer = w
+	return
+}
+
+func (w *journaldWriter) validate() error {
+	if w.Socket == "" {
+		return newConfigError(AdapterJournald, "socket", "must not be empty")
+	}
+	if w.Identifier == "" || strings.ContainsRune(w.Identifier, '\n') {
+		return newConfigError(AdapterJournald, "identifier", "must be one line and not empty")
+	}
+	return nil
+}
+
+func getJournaldWriter() *journaldWriter {
+	return &journaldWriter{
+		Socket:     journaldSocket,
+		Identifier: filepath.Base(os.Args[0]),
+		level:      LevelInfo,
+	}
+}
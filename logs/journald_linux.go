@@ -0,0 +1,102 @@
+//go:build linux
+// +build linux
+
+package logs
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+// memfd_create is not in syscall package
+var memfdCreateTrap = map[string]uintptr{
+	"386":     356,
+	"amd64":   319,
+	"arm":     385,
+	"arm64":   279,
+	"loong64": 279,
+	"ppc64":   360,
+	"ppc64le": 360,
+	"riscv64": 279,
+	"s390x":   350,
+}
+
+const (
+	mfdCloexec      = 0x1
+	mfdAllowSealing = 0x2
+
+	fcntlAddSeals = 1033
+	// seal, shrink, grow and write
+	sealAll = 0xf
+)
+
+// journaldSendFile write data to a sealed memfd, or an unlinked file in /dev/shm
+// if memfd is not available, and send its descriptor to journald
+func journaldSendFile(conn *net.UnixConn, data []byte) error {
+	f, err := memfdFile(data)
+	if err != nil {
+		if f, err = ioutil.TempFile("/dev/shm", "journal-"); err != nil {
+			return err
+		}
+		_ = os.Remove(f.Name())
+		if _, err = f.Write(data); err != nil {
+			_ = f.Close()
+			return err
+		}
+	}
+	defer f.Close()
+
+	// WriteMsgUnix refuse connected datagram socket
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	rights := syscall.UnixRights(int(f.Fd()))
+	writeErr := raw.Write(func(fd uintptr) bool {
+		err = syscall.Sendmsg(int(fd), nil, rights, nil, 0)
+		return err != syscall.EAGAIN
+	})
+	if writeErr != nil {
+		return writeErr
+	}
+	return err
+}
+
+// isMessageTooLarge report whether err means the datagram is too large to send
+func isMessageTooLarge(err error) bool {
+	if opErr, ok := err.(*net.OpError); ok {
+		err = opErr.Err
+	}
+	if sysErr, ok := err.(*os.SyscallError); ok {
+		err = sysErr.Err
+	}
+	return err == syscall.EMSGSIZE || err == syscall.ENOBUFS
+}
+
+func memfdFile(data []byte) (*os.File, error) {
+	trap, ok := memfdCreateTrap[runtime.GOARCH]
+	if !ok {
+		return nil, syscall.ENOSYS
+	}
+
+	name, _ := syscall.BytePtrFromString("journal")
+	fd, _, errno := syscall.Syscall(trap, uintptr(unsafe.Pointer(name)), mfdCloexec|mfdAllowSealing, 0)
+	if errno != 0 {
+		return nil, errno
+	}
+	f := os.NewFile(fd, "journal")
+
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	if _, _, errno = syscall.Syscall(syscall.SYS_FCNTL, fd, fcntlAddSeals, sealAll); errno != 0 {
+		_ = f.Close()
+		return nil, errno
+	}
+	return f, nil
+}
@@ -0,0 +1,19 @@
+//go:build !linux
+// +build !linux
+
+package logs
+
+import (
+	"errors"
+	"net"
+)
+
+// journaldSendFile is only supported on linux, journald does not run elsewhere
+func journaldSendFile(conn *net.UnixConn, data []byte) error {
+	return errors.New("journald adapter: message too large")
+}
+
+// isMessageTooLarge is always false, as a large message can not be sent by file descriptor
+func isMessageTooLarge(err error) bool {
+	return false
+}
@@ -0,0 +1,134 @@
+//go:build linux
+// +build linux
+
+package logs
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// listenJournal bind a unixgram socket as journald
+func listenJournal(t *testing.T, name string) *net.UnixConn {
+	_ = os.MkdirAll("./journald", 0755)
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: name, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+// readJournal read one record, the record is read from the passed file if the datagram is empty
+func readJournal(t *testing.T, conn *net.UnixConn) map[string]string {
+	buf := make([]byte, 65536)
+	oob := make([]byte, 64)
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, oobn, _, _, err := conn.ReadMsgUnix(buf, oob)
+	if err != nil {
+		t.Fatal("no record received:", err)
+	}
+	data := buf[:n]
+
+	if n == 0 {
+		msgs, err := syscall.ParseSocketControlMessage(oob[:oobn])
+		if err != nil || len(msgs) != 1 {
+			t.Fatal("no file received:", err)
+		}
+		fds, err := syscall.ParseUnixRights(&msgs[0])
+		if err != nil || len(fds) != 1 {
+			t.Fatal("no file received:", err)
+		}
+		// journald read the file from start
+		f := os.NewFile(uintptr(fds[0]), "journal")
+		_, _ = f.Seek(0, 0)
+		data, _ = ioutil.ReadAll(f)
+		_ = f.Close()
+	}
+	return parseJournal(data)
+}
+
+func parseJournal(data []byte) map[string]string {
+	record := make(map[string]string)
+	for len(data) > 0 {
+		i := bytes.IndexByte(data, '\n')
+		line := string(data[:i])
+		data = data[i+1:]
+		if j := strings.IndexByte(line, '='); j >= 0 {
+			record[line[:j]] = line[j+1:]
+			continue
+		}
+		size := binary.LittleEndian.Uint64(data)
+		record[line] = string(data[8 : 8+size])
+		data = data[8+size+1:]
+	}
+	return record
+}
+
+func TestJournald(t *testing.T) {
+	conn := listenJournal(t, "./journald/socket")
+	defer conn.Close()
+
+	log := NewLogger()
+	err := log.AddAdapter(AdapterJournald, LevelInfoStr, `{"socket":"./journald/socket", "identifier":"app"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log.WithFields(Fields{"request-id": "abc", "_uid": 0}).Error("denied\nstack")
+	log.Info("info")
+	log.Close()
+
+	record := readJournal(t, conn)
+	if record["MESSAGE"] != "denied\nstack" || record["PRIORITY"] != "3" || record["SYSLOG_IDENTIFIER"] != "app" {
+		t.Error("wrong record:", record)
+	}
+	if record["REQUEST_ID"] != "abc" || record["UID"] != "0" {
+		t.Error("wrong fields:", record)
+	}
+	if !strings.HasSuffix(record["CODE_FILE"], "journald_test.go") || record["CODE_LINE"] == "" {
+		t.Error("wrong caller:", record)
+	}
+	if record = readJournal(t, conn); record["MESSAGE"] != "info" || record["PRIORITY"] != "6" {
+		t.Error("wrong record:", record)
+	}
+	_ = os.RemoveAll("./journald/")
+}
+
+func TestJournaldLargeMessage(t *testing.T) {
+	conn := listenJournal(t, "./journald/large")
+	defer conn.Close()
+
+	log := NewLogger()
+	if err := log.AddAdapter(AdapterJournald, LevelInfoStr, `{"socket":"./journald/large"}`); err != nil {
+		t.Fatal(err)
+	}
+	message := strings.Repeat("0123456789", 1<<20/10)
+	log.Info(message)
+	log.Close()
+
+	if record := readJournal(t, conn); record["MESSAGE"] != message {
+		t.Error("large record not received, length " + strconv.Itoa(len(record["MESSAGE"])))
+	}
+	_ = os.RemoveAll("./journald/")
+}
+
+func TestJournaldInvalid(t *testing.T) {
+	log := NewLogger()
+	cases := map[string]string{
+		`{"socket":""}`: "socket",
+		`{"socket":"/dev/null", "identifier":""}`: "identifier",
+	}
+	for helper, field := range cases {
+		err := log.AddAdapter(AdapterJournald, LevelInfoStr, helper)
+		if cErr, ok := err.(*ConfigError); !ok || cErr.Field != field {
+			t.Error(helper, "not get ConfigError on", field, "but", err)
+		}
+	}
+}
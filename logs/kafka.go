@@ -0,0 +1,285 @@
+package logs
+
+import (
+	"crypto/tls"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	KafkaFormatJSON = "json"
+	KafkaFormatText = "text"
+)
+
+// KafkaMessage is one record to produce
+type KafkaMessage struct {
+	// Nil key means no key, the partition is chosen by round robin
+	Key   []byte
+	Value []byte
+	Time  time.Time
+}
+
+// KafkaProducer produce messages to kafka, it is called in one goroutine.
+// Produce should return after messages are acknowledged as KafkaConfig.Acks
+type KafkaProducer interface {
+	Produce(topic string, messages []KafkaMessage) error
+	Close() error
+}
+
+// KafkaConfig is passed to KafkaClient to create producer
+type KafkaConfig struct {
+	Brokers []string
+	// 0, 1 or -1 means all in-sync replicas
+	Acks int
+	// none, gzip, snappy, lz4 or zstd
+	Compression string
+	Timeout     time.Duration
+	// Nil means plain tcp
+	TLS *tls.Config
+}
+
+// KafkaClient create producer, an error of *ConfigError means the config is not supported
+type KafkaClient func(config KafkaConfig) (KafkaProducer, error)
+
+var (
+	kafkaClientsLock sync.RWMutex
+	kafkaClients     = map[string]KafkaClient{"builtin": newKafkaBuiltinProducer}
+)
+
+// RegisterKafkaClient make client usable by "client" option of kafka adapter,
+// like a wrapper of a full featured kafka library
+func RegisterKafkaClient(name string, client KafkaClient) {
+	kafkaClientsLock.Lock()
+	kafkaClients[name] = client
+	kafkaClientsLock.Unlock()
+}
+
+type kafkaWriter struct {
+	// first field to make sure 64-bit aligned
+	counter deliveryCounter
+
+	level int
+
+	Brokers []string `json:"brokers"`
+	Topic   string   `json:"topic"`
+	// Record field used as message key, empty means no key
+	KeyField string `json:"keyfield"`
+	// Value format, json or text
+	Format string `json:"format"`
+	// "0", "1" or "all"
+	Acks        string   `json:"acks"`
+	Compression string   `json:"compression"`
+	Timeout     Duration `json:"timeout"`
+	TLS         bool     `json:"tls"`
+	tlsOptions
+	// Registered KafkaClient name
+	Client string `json:"client"`
+
+	// Retry every failure as broker may come back
+	batchOptions
+
+	// Max records kept in memory after retries failed, they are produced
+	// before next batch. Zero means drop them
+	BufferSize int `json:"buffersize"`
+	buffer     []KafkaMessage
+
+	producer KafkaProducer
+	batcher  *batcher
+	errorReporter
+}
+
+func (w *kafkaWriter) WriteMsg(message logMessage) error {
+	if message.level < w.level {
+		return nil
+	}
+
+	var value []byte
+	if w.Format == KafkaFormatJSON {
+		value = jsonMessage(w.level, message)
+	} else {
+		value = []byte(strings.TrimSuffix(textMessage(w.level, message), "\n"))
+	}
+
+	err := w.batcher.add(batchRecord{message: message, data: value})
+	if err != nil {
+		atomic.AddUint64(&w.counter.dropped, 1)
+	}
+	return err
+}
+
+func (w *kafkaWriter) Flush() {
+	w.batcher.flush()
+}
+
+func (w *kafkaWriter) Destroy() {
+	w.batcher.close()
+	_ = w.producer.Close()
+}
+
+func (w *kafkaWriter) reportError(err error) {
+	w.counter.setError(err)
+	w.errorReporter.reportError(err)
+}
+
+func (w *kafkaWriter) deliveryStats() DeliveryStats {
+	return w.counter.stats(AdapterKafka)
+}
+
+// key return the message key from KeyField
+func (w *kafkaWriter) key(message logMessage) []byte {
+	if w.KeyField == "" {
+		return nil
+	}
+	v, ok := message.fields[w.KeyField]
+	if !ok {
+		return nil
+	}
+	return []byte(fmt.Sprintf("%+v", v))
+}
+
+// send is called by batcher in its goroutine
+func (w *kafkaWriter) send(batch []batchRecord) {
+	messages := make([]KafkaMessage, len(batch))
+	for i, record := range batch {
+		messages[i] = KafkaMessage{Key: w.key(record.message), Value: record.data, Time: record.message.time}
+	}
+
+	// buffered messages must be produced first to keep order
+	if len(w.buffer) > 0 {
+		if err := w.produce(w.buffer); err != nil {
+			w.reportError(err)
+			w.keep(messages)
+			return
+		}
+		w.buffer = nil
+	}
+
+	if err := w.produce(messages); err != nil {
+		w.reportError(err)
+		w.keep(messages)
+	}
+}
+
+func (w *kafkaWriter) produce(messages []KafkaMessage) error {
+	err := w.retry(&w.counter, func() (bool, error) {
+		return true, w.producer.Produce(w.Topic, messages)
+	})
+	if err == nil {
+		atomic.AddUint64(&w.counter.sent, uint64(len(messages)))
+		atomic.AddUint64(&w.counter.batches, 1)
+	}
+	return err
+}
+
+// keep messages in buffer, oldest ones are dropped when buffer is full
+func (w *kafkaWriter) keep(messages []KafkaMessage) {
+	w.buffer = append(w.buffer, messages...)
+	// messages dropped at once are failed only
+	kept := len(messages)
+	if kept > w.BufferSize {
+		kept = w.BufferSize
+	}
+	atomic.AddUint64(&w.counter.spooled, uint64(kept))
+	if over := len(w.buffer) - w.BufferSize; over > 0 {
+		atomic.AddUint64(&w.counter.failed, uint64(over))
+		w.buffer = append([]KafkaMessage(nil), w.buffer[over:]...)
+	}
+}
+
+func newKafkaAdapter(level string, helper string) (writer *kafkaWriter, err error) {
+	w := getKafkaWriter()
+
+	if err = decodeHelper(AdapterKafka, helper, w); err != nil {
+		return
+	}
+
+	if w.level = getLevelInt(level); w.level == -1 {
+		err = NoSupportLevel
+		return
+	}
+
+	config, client, err := w.validate()
+	if err != nil {
+		return
+	}
+
+	if w.producer, err = client(config); err != nil {
+		return
+	}
+	w.batcher = w.newBatcher(w.send)
+
+	writer = w
+	return
+}
+
+func (w *kafkaWriter) validate() (config KafkaConfig, client KafkaClient, err error) {
+	if len(w.Brokers) == 0 {
+		err = newConfigError(AdapterKafka, "brokers", "must not be empty")
+		return
+	}
+	if w.Topic == "" {
+		err = newConfigError(AdapterKafka, "topic", "must not be empty")
+		return
+	}
+	switch w.Format {
+	case KafkaFormatJSON, KafkaFormatText:
+	default:
+		err = newConfigError(AdapterKafka, "format", "unknown format %q", w.Format)
+		return
+	}
+
+	config = KafkaConfig{Brokers: w.Brokers, Compression: w.Compression, Timeout: time.Duration(w.Timeout)}
+	switch w.Acks {
+	case "0":
+		config.Acks = 0
+	case "1":
+		config.Acks = 1
+	case "all", "-1":
+		config.Acks = -1
+	default:
+		err = newConfigError(AdapterKafka, "acks", "unknown acks %q", w.Acks)
+		return
+	}
+	switch w.Compression {
+	case "none", "gzip", "snappy", "lz4", "zstd":
+	default:
+		err = newConfigError(AdapterKafka, "compression", "unknown compression %q", w.Compression)
+		return
+	}
+	if w.TLS {
+		if config.TLS, err = w.tlsOptions.tlsConfig(AdapterKafka); err != nil {
+			return
+		}
+	}
+
+	kafkaClientsLock.RLock()
+	client = kafkaClients[w.Client]
+	kafkaClientsLock.RUnlock()
+	if client == nil {
+		err = newConfigError(AdapterKafka, "client", "%q is not registered", w.Client)
+		return
+	}
+
+	if w.BufferSize < 0 {
+		err = newConfigError(AdapterKafka, "buffersize", "must not be negative")
+		return
+	}
+	err = w.batchOptions.validate(AdapterKafka)
+	return
+}
+
+func getKafkaWriter() *kafkaWriter {
+	return &kafkaWriter{
+		Format:       KafkaFormatJSON,
+		Acks:         "1",
+		Compression:  "none",
+		Timeout:      Duration(10 * time.Second),
+		Client:       "builtin",
+		batchOptions: defaultBatchOptions(),
+		BufferSize:   10000,
+		level:        LevelInfo,
+	}
+}
@@ -0,0 +1,534 @@
+package logs
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// builtin client speak the minimal kafka protocol to produce:
+// Metadata v1 to find partition leaders and Produce v3 with record batch v2,
+// no sasl and only gzip compression
+
+const (
+	kafkaAPIProduce  = 0
+	kafkaAPIMetadata = 3
+	kafkaClientID    = "golib-logs"
+	// larger response is not from kafka, like a http server, the same as sarama MaxResponseSize
+	kafkaMaxResponseSize = 100 << 20
+
+	kafkaCompressGzip = 1
+)
+
+var kafkaErrorNames = map[int16]string{
+	1:  "OFFSET_OUT_OF_RANGE",
+	2:  "CORRUPT_MESSAGE",
+	3:  "UNKNOWN_TOPIC_OR_PARTITION",
+	5:  "LEADER_NOT_AVAILABLE",
+	6:  "NOT_LEADER_OR_FOLLOWER",
+	7:  "REQUEST_TIMED_OUT",
+	10: "MESSAGE_TOO_LARGE",
+	19: "NOT_ENOUGH_REPLICAS",
+	20: "NOT_ENOUGH_REPLICAS_AFTER_APPEND",
+	29: "TOPIC_AUTHORIZATION_FAILED",
+	87: "INVALID_RECORD",
+}
+
+var kafkaCastagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// KafkaError is an error code responded by broker
+type KafkaError struct {
+	Topic     string
+	Partition int32
+	Code      int16
+}
+
+func (e *KafkaError) Error() string {
+	name, ok := kafkaErrorNames[e.Code]
+	if !ok {
+		name = "error code " + strconv.Itoa(int(e.Code))
+	}
+	return fmt.Sprintf("kafka: %s of topic %s partition %d", name, e.Topic, e.Partition)
+}
+
+type kafkaBuiltinProducer struct {
+	config KafkaConfig
+	// conn of every broker address
+	conns map[string]*kafkaConn
+	// leader address of every partition of topic, empty if no leader
+	leaders map[string][]string
+	// partition of messages without key
+	next int
+}
+
+func newKafkaBuiltinProducer(config KafkaConfig) (KafkaProducer, error) {
+	switch config.Compression {
+	case "none", "gzip":
+	default:
+		return nil, newConfigError(AdapterKafka, "compression", "builtin client only support none and gzip")
+	}
+	return &kafkaBuiltinProducer{
+		config:  config,
+		conns:   make(map[string]*kafkaConn),
+		leaders: make(map[string][]string),
+	}, nil
+}
+
+// Produce send messages by leaders, messages may be duplicated if some of leaders failed
+func (p *kafkaBuiltinProducer) Produce(topic string, messages []KafkaMessage) (err error) {
+	leaders, ok := p.leaders[topic]
+	if !ok {
+		if leaders, err = p.metadata(topic); err != nil {
+			return
+		}
+		p.leaders[topic] = leaders
+	}
+
+	// address -> partition -> messages
+	requests := make(map[string]map[int32][]KafkaMessage)
+	unkeyed := int32(p.next % len(leaders))
+	p.next++
+	for _, m := range messages {
+		partition := unkeyed
+		if m.Key != nil {
+			partition = (kafkaMurmur2(m.Key) & 0x7fffffff) % int32(len(leaders))
+		}
+		address := leaders[partition]
+		if address == "" {
+			delete(p.leaders, topic)
+			return &KafkaError{Topic: topic, Partition: partition, Code: 5}
+		}
+		if requests[address] == nil {
+			requests[address] = make(map[int32][]KafkaMessage)
+		}
+		requests[address][partition] = append(requests[address][partition], m)
+	}
+
+	addresses := make([]string, 0, len(requests))
+	for address := range requests {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+	for _, address := range addresses {
+		if err = p.produce(address, topic, requests[address]); err != nil {
+			// leader may be changed
+			delete(p.leaders, topic)
+			return
+		}
+	}
+	return
+}
+
+func (p *kafkaBuiltinProducer) produce(address string, topic string, partitions map[int32][]KafkaMessage) error {
+	indexes := make([]int, 0, len(partitions))
+	for partition := range partitions {
+		indexes = append(indexes, int(partition))
+	}
+	sort.Ints(indexes)
+
+	var e kafkaEncoder
+	e.int16(-1) // no transactional id
+	e.int16(int16(p.config.Acks))
+	e.int32(int32(p.config.Timeout / time.Millisecond))
+	e.int32(1)
+	e.string(topic)
+	e.int32(int32(len(indexes)))
+	for _, partition := range indexes {
+		batch, err := kafkaRecordBatch(partitions[int32(partition)], p.config.Compression)
+		if err != nil {
+			return err
+		}
+		e.int32(int32(partition))
+		e.bytes(batch)
+	}
+
+	resp, err := p.request(address, kafkaAPIProduce, 3, e.buf, p.config.Acks != 0)
+	if err != nil || p.config.Acks == 0 {
+		return err
+	}
+
+	d := kafkaDecoder{buf: resp}
+	for i := d.array(); i > 0; i-- {
+		name := d.string()
+		for j := d.array(); j > 0; j-- {
+			partition, code := d.int32(), d.int16()
+			d.int64() // base offset
+			d.int64() // log append time
+			if code != 0 && d.err == nil {
+				return &KafkaError{Topic: name, Partition: partition, Code: code}
+			}
+		}
+	}
+	return d.err
+}
+
+// metadata return leader address of every partition of topic
+func (p *kafkaBuiltinProducer) metadata(topic string) (leaders []string, err error) {
+	var e kafkaEncoder
+	e.int32(1)
+	e.string(topic)
+
+	for _, broker := range p.config.Brokers {
+		leaders = nil
+		var resp []byte
+		if resp, err = p.request(broker, kafkaAPIMetadata, 1, e.buf, true); err != nil {
+			continue
+		}
+
+		d := kafkaDecoder{buf: resp}
+		brokers := make(map[int32]string)
+		for i := d.array(); i > 0; i-- {
+			node, host, port := d.int32(), d.string(), d.int32()
+			d.nullableString() // rack
+			brokers[node] = net.JoinHostPort(host, strconv.Itoa(int(port)))
+		}
+		d.int32() // controller
+
+		for i := d.array(); i > 0; i-- {
+			code, name := d.int16(), d.string()
+			d.int8() // is internal
+			for j := d.array(); j > 0; j-- {
+				d.int16() // partition error, leader is -1 if there is no leader
+				partition, leader := d.int32(), d.int32()
+				d.int32Array() // replicas
+				d.int32Array() // isr
+				if d.err != nil || partition < 0 || partition > 1<<16 {
+					break
+				}
+				for int(partition) >= len(leaders) {
+					leaders = append(leaders, "")
+				}
+				leaders[partition] = brokers[leader]
+			}
+			if code != 0 && name == topic && d.err == nil {
+				return nil, &KafkaError{Topic: topic, Partition: -1, Code: code}
+			}
+		}
+		if d.err != nil {
+			err = d.err
+			continue
+		}
+		if len(leaders) == 0 {
+			return nil, &KafkaError{Topic: topic, Partition: -1, Code: 3}
+		}
+		return leaders, nil
+	}
+	return nil, fmt.Errorf("kafka: no broker available: %s", err)
+}
+
+// request send request to broker at address and return response body after correlation id
+func (p *kafkaBuiltinProducer) request(address string, apiKey int16, version int16, body []byte, response bool) (resp []byte, err error) {
+	conn, ok := p.conns[address]
+	if !ok {
+		c, err := dialConn("tcp", address, p.config.Timeout, p.config.TLS)
+		if err != nil {
+			return nil, err
+		}
+		conn = &kafkaConn{conn: c}
+		p.conns[address] = conn
+	}
+
+	if resp, err = conn.request(apiKey, version, body, response, p.config.Timeout); err != nil {
+		_ = conn.conn.Close()
+		delete(p.conns, address)
+	}
+	return
+}
+
+func (p *kafkaBuiltinProducer) Close() error {
+	for address, conn := range p.conns {
+		_ = conn.conn.Close()
+		delete(p.conns, address)
+	}
+	return nil
+}
+
+type kafkaConn struct {
+	conn        net.Conn
+	correlation int32
+}
+
+func (c *kafkaConn) request(apiKey int16, version int16, body []byte, response bool, timeout time.Duration) ([]byte, error) {
+	c.correlation++
+
+	var e kafkaEncoder
+	e.int32(0) // size, set later
+	e.int16(apiKey)
+	e.int16(version)
+	e.int32(c.correlation)
+	e.string(kafkaClientID)
+	e.buf = append(e.buf, body...)
+	binary.BigEndian.PutUint32(e.buf, uint32(len(e.buf)-4))
+
+	if timeout > 0 {
+		_ = c.conn.SetDeadline(time.Now().Add(timeout))
+	}
+	if _, err := c.conn.Write(e.buf); err != nil || !response {
+		return nil, err
+	}
+
+	size := make([]byte, 4)
+	if _, err := io.ReadFull(c.conn, size); err != nil {
+		return nil, err
+	}
+	n := binary.BigEndian.Uint32(size)
+	if n > kafkaMaxResponseSize {
+		return nil, fmt.Errorf("kafka: response of %d bytes is too large, is it a kafka broker?", n)
+	}
+	resp := make([]byte, n)
+	if _, err := io.ReadFull(c.conn, resp); err != nil {
+		return nil, err
+	}
+	if len(resp) < 4 || int32(binary.BigEndian.Uint32(resp)) != c.correlation {
+		return nil, errors.New("kafka: response correlation id mismatch")
+	}
+	return resp[4:], nil
+}
+
+// kafkaRecordBatch encode messages as one record batch v2
+func kafkaRecordBatch(messages []KafkaMessage, compression string) ([]byte, error) {
+	first := kafkaTimestamp(messages[0].Time)
+	max := first
+
+	var records kafkaEncoder
+	for i, m := range messages {
+		ts := kafkaTimestamp(m.Time)
+		if ts > max {
+			max = ts
+		}
+
+		var r kafkaEncoder
+		r.int8(0) // attributes
+		r.varint(ts - first)
+		r.varint(int64(i))
+		if m.Key == nil {
+			r.varint(-1)
+		} else {
+			r.varint(int64(len(m.Key)))
+			r.buf = append(r.buf, m.Key...)
+		}
+		r.varint(int64(len(m.Value)))
+		r.buf = append(r.buf, m.Value...)
+		r.varint(0) // headers
+
+		records.varint(int64(len(r.buf)))
+		records.buf = append(records.buf, r.buf...)
+	}
+
+	var attributes int16
+	data := records.buf
+	if compression == "gzip" {
+		var err error
+		if data, err = gzipBytes(data); err != nil {
+			return nil, err
+		}
+		attributes = kafkaCompressGzip
+	}
+
+	// the part covered by crc
+	var c kafkaEncoder
+	c.int16(attributes)
+	c.int32(int32(len(messages) - 1))
+	c.int64(first)
+	c.int64(max)
+	c.int64(-1) // producer id
+	c.int16(-1) // producer epoch
+	c.int32(-1) // base sequence
+	c.int32(int32(len(messages)))
+	c.buf = append(c.buf, data...)
+
+	var b kafkaEncoder
+	b.int64(0) // base offset
+	b.int32(int32(4 + 1 + 4 + len(c.buf)))
+	b.int32(-1) // partition leader epoch
+	b.int8(2)   // magic
+	b.int32(int32(crc32.Checksum(c.buf, kafkaCastagnoli)))
+	b.buf = append(b.buf, c.buf...)
+	return b.buf, nil
+}
+
+func kafkaTimestamp(t time.Time) int64 {
+	if t.IsZero() {
+		t = time.Now()
+	}
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+// kafkaMurmur2 is the hash used by java client to choose partition of key
+func kafkaMurmur2(data []byte) int32 {
+	const (
+		seed uint32 = 0x9747b28c
+		m    uint32 = 0x5bd1e995
+	)
+
+	length := len(data)
+	h := seed ^ uint32(length)
+	for i := 0; i+4 <= length; i += 4 {
+		k := binary.LittleEndian.Uint32(data[i:])
+		k *= m
+		k ^= k >> 24
+		k *= m
+		h *= m
+		h ^= k
+	}
+
+	tail := data[length&^3:]
+	switch len(tail) {
+	case 3:
+		h ^= uint32(tail[2]) << 16
+		fallthrough
+	case 2:
+		h ^= uint32(tail[1]) << 8
+		fallthrough
+	case 1:
+		h ^= uint32(tail[0])
+		h *= m
+	}
+
+	h ^= h >> 13
+	h *= m
+	h ^= h >> 15
+	return int32(h)
+}
+
+type kafkaEncoder struct {
+	buf []byte
+}
+
+func (e *kafkaEncoder) int8(v int8) {
+	e.buf = append(e.buf, byte(v))
+}
+
+func (e *kafkaEncoder) int16(v int16) {
+	e.buf = append(e.buf, byte(v>>8), byte(v))
+}
+
+func (e *kafkaEncoder) int32(v int32) {
+	e.buf = append(e.buf, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func (e *kafkaEncoder) int64(v int64) {
+	e.int32(int32(v >> 32))
+	e.int32(int32(v))
+}
+
+func (e *kafkaEncoder) string(s string) {
+	e.int16(int16(len(s)))
+	e.buf = append(e.buf, s...)
+}
+
+func (e *kafkaEncoder) bytes(b []byte) {
+	e.int32(int32(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+// varint is zigzag encoded
+func (e *kafkaEncoder) varint(v int64) {
+	var b [binary.MaxVarintLen64]byte
+	e.buf = append(e.buf, b[:binary.PutVarint(b[:], v)]...)
+}
+
+// kafkaDecoder read from buf, the first error is kept in err and later reads return zero
+type kafkaDecoder struct {
+	buf []byte
+	err error
+}
+
+func (d *kafkaDecoder) next(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || len(d.buf) < n {
+		d.err = errors.New("kafka: malformed response")
+		return nil
+	}
+	b := d.buf[:n]
+	d.buf = d.buf[n:]
+	return b
+}
+
+func (d *kafkaDecoder) int8() int8 {
+	if b := d.next(1); b != nil {
+		return int8(b[0])
+	}
+	return 0
+}
+
+func (d *kafkaDecoder) int16() int16 {
+	if b := d.next(2); b != nil {
+		return int16(binary.BigEndian.Uint16(b))
+	}
+	return 0
+}
+
+func (d *kafkaDecoder) int32() int32 {
+	if b := d.next(4); b != nil {
+		return int32(binary.BigEndian.Uint32(b))
+	}
+	return 0
+}
+
+func (d *kafkaDecoder) int64() int64 {
+	if b := d.next(8); b != nil {
+		return int64(binary.BigEndian.Uint64(b))
+	}
+	return 0
+}
+
+func (d *kafkaDecoder) string() string {
+	return string(d.next(int(d.int16())))
+}
+
+func (d *kafkaDecoder) nullableString() string {
+	n := d.int16()
+	if n < 0 {
+		return ""
+	}
+	return string(d.next(int(n)))
+}
+
+func (d *kafkaDecoder) bytes() []byte {
+	n := d.int32()
+	if n < 0 {
+		return nil
+	}
+	return d.next(int(n))
+}
+
+func (d *kafkaDecoder) varint() int64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(d.buf)
+	if n <= 0 {
+		d.err = errors.New("kafka: malformed varint")
+		return 0
+	}
+	d.buf = d.buf[n:]
+	return v
+}
+
+// array return count of array, zero for null array
+func (d *kafkaDecoder) array() int {
+	n := d.int32()
+	if n < 0 || d.err != nil {
+		return 0
+	}
+	if int(n) > len(d.buf) {
+		d.err = errors.New("kafka: malformed array")
+		return 0
+	}
+	return int(n)
+}
+
+func (d *kafkaDecoder) int32Array() {
+	for i := d.array(); i > 0; i-- {
+		d.int32()
+	}
+}
@@ -0,0 +1,360 @@
+package logs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// kafkaStub is a producer record messages, fail the first failures produces
+type kafkaStub struct {
+	sync.Mutex
+	config   KafkaConfig
+	messages []KafkaMessage
+	failures int
+	closed   bool
+}
+
+func (s *kafkaStub) Produce(topic string, messages []KafkaMessage) error {
+	s.Lock()
+	defer s.Unlock()
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("broker unavailable")
+	}
+	s.messages = append(s.messages, messages...)
+	return nil
+}
+
+func (s *kafkaStub) Close() error {
+	s.closed = true
+	return nil
+}
+
+func TestKafkaStub(t *testing.T) {
+	stub := &kafkaStub{failures: 2}
+	RegisterKafkaClient("stub", func(config KafkaConfig) (KafkaProducer, error) {
+		stub.config = config
+		return stub, nil
+	})
+
+	log := NewLogger()
+	log.OnError(func(error) {})
+	err := log.AddAdapter(AdapterKafka, LevelInfoStr, `{"client":"stub", "brokers":["b1:9092"], "topic":"logs",
+		"keyfield":"user", "acks":"all", "format":"text", "retries":1, "backoff":"1ms", "batchsize":1, "buffersize":1}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stub.config.Acks != -1 || stub.config.Brokers[0] != "b1:9092" {
+		t.Error("wrong config:", stub.config)
+	}
+
+	// first failed after retry and buffered
+	log.WithFields(Fields{"user": "box"}).Info("first")
+	log.Info("second")
+	log.Close()
+
+	if len(stub.messages) != 2 || !stub.closed {
+		t.Fatal("wrong messages:", stub.messages)
+	}
+	if string(stub.messages[0].Key) != "box" || !bytes.HasSuffix(stub.messages[0].Value, []byte(" - first user=box")) {
+		t.Error("wrong first message:", string(stub.messages[0].Key), string(stub.messages[0].Value))
+	}
+	if stub.messages[1].Key != nil || !bytes.HasSuffix(stub.messages[1].Value, []byte(" - second")) {
+		t.Error("wrong second message:", string(stub.messages[1].Value))
+	}
+	stats := log.DeliveryStats()
+	if stats[0].Sent != 2 || stats[0].Spooled != 1 || stats[0].Retries != 1 || stats[0].Failed != 0 {
+		t.Error("wrong stats:", stats)
+	}
+}
+
+func TestKafkaNoBuffer(t *testing.T) {
+	stub := &kafkaStub{failures: 100}
+	RegisterKafkaClient("stub-nobuffer", func(config KafkaConfig) (KafkaProducer, error) {
+		return stub, nil
+	})
+
+	log := NewLogger()
+	log.OnError(func(error) {})
+	err := log.AddAdapter(AdapterKafka, LevelInfoStr, `{"client":"stub-nobuffer", "brokers":["b1:9092"], "topic":"logs",
+		"retries":0, "batchsize":1, "buffersize":0}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log.Info("first")
+	log.Info("second")
+	log.Close()
+
+	// dropped at once, never spooled
+	if stats := log.DeliveryStats(); stats[0].Spooled != 0 || stats[0].Failed != 2 {
+		t.Error("wrong stats:", stats)
+	}
+}
+
+func TestKafkaResponseTooLarge(t *testing.T) {
+	// not a kafka broker, the response size is "HTTP"
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = conn.Write([]byte("HTTP/1.1 400 Bad Request\r\n\r\n"))
+		_, _ = io.Copy(ioutil.Discard, conn)
+	}()
+
+	c, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	conn := &kafkaConn{conn: c}
+	if _, err = conn.request(kafkaAPIMetadata, 1, nil, true, time.Second); err == nil || !strings.Contains(err.Error(), "too large") {
+		t.Error("too large response not rejected:", err)
+	}
+}
+
+func TestKafkaMurmur2(t *testing.T) {
+	// from kafka java client tests
+	cases := map[string]int32{
+		"21":                         -973932308,
+		"foobar":                     -790332482,
+		"a-little-bit-long-string":   -985981536,
+		"a-little-bit-longer-string": -1486304829,
+		"abc":                        479470107,
+	}
+	for key, expected := range cases {
+		if h := kafkaMurmur2([]byte(key)); h != expected {
+			t.Error(key, "hash to", h, "not", expected)
+		}
+	}
+}
+
+// fakeKafka is a broker with 2 partitions of every topic, record values produced to each partition
+type fakeKafka struct {
+	sync.Mutex
+	ln         net.Listener
+	partitions [2][]string
+	keys       [2][]string
+	acks       []int16
+}
+
+func newFakeKafka(t *testing.T) *fakeKafka {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	k := &fakeKafka{ln: ln}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go k.serve(t, conn)
+		}
+	}()
+	return k
+}
+
+func (k *fakeKafka) serve(t *testing.T, conn net.Conn) {
+	defer conn.Close()
+	for {
+		size := make([]byte, 4)
+		if _, err := io.ReadFull(conn, size); err != nil {
+			return
+		}
+		req := make([]byte, binary.BigEndian.Uint32(size))
+		if _, err := io.ReadFull(conn, req); err != nil {
+			return
+		}
+
+		d := kafkaDecoder{buf: req}
+		apiKey, version, correlation := d.int16(), d.int16(), d.int32()
+		d.string() // client id
+
+		var e kafkaEncoder
+		e.int32(0)
+		e.int32(correlation)
+		switch {
+		case apiKey == kafkaAPIMetadata && version == 1:
+			d.array()
+			topic := d.string()
+			host, port, _ := net.SplitHostPort(k.ln.Addr().String())
+			portNumber, _ := strconv.Atoi(port)
+			e.int32(1)
+			e.int32(0)
+			e.string(host)
+			e.int32(int32(portNumber))
+			e.int16(-1)
+			e.int32(0) // controller
+			e.int32(1)
+			e.int16(0)
+			e.string(topic)
+			e.int8(0)
+			e.int32(2)
+			for partition := int32(0); partition < 2; partition++ {
+				e.int16(0)
+				e.int32(partition)
+				e.int32(0)
+				e.int32(1)
+				e.int32(0)
+				e.int32(1)
+				e.int32(0)
+			}
+		case apiKey == kafkaAPIProduce && version == 3:
+			d.nullableString()
+			acks := d.int16()
+			d.int32()
+			d.array()
+			topic := d.string()
+			e.int32(1)
+			e.string(topic)
+			count := d.array()
+			e.int32(int32(count))
+			for ; count > 0; count-- {
+				partition := d.int32()
+				code := k.records(partition, d.bytes())
+				e.int32(partition)
+				e.int16(code)
+				e.int64(0)
+				e.int64(-1)
+			}
+			e.int32(0) // throttle
+			k.Lock()
+			k.acks = append(k.acks, acks)
+			k.Unlock()
+			if acks == 0 {
+				continue
+			}
+		default:
+			t.Error("unexpected request", apiKey, version)
+			return
+		}
+		if d.err != nil {
+			t.Error(d.err)
+			return
+		}
+		binary.BigEndian.PutUint32(e.buf, uint32(len(e.buf)-4))
+		_, _ = conn.Write(e.buf)
+	}
+}
+
+// records decode record batch, return error code
+func (k *fakeKafka) records(partition int32, batch []byte) int16 {
+	d := kafkaDecoder{buf: batch}
+	d.int64()
+	if int(d.int32()) != len(d.buf) {
+		return 87
+	}
+	d.int32()
+	if d.int8() != 2 {
+		return 87
+	}
+	if uint32(d.int32()) != crc32.Checksum(d.buf, crc32.MakeTable(crc32.Castagnoli)) {
+		return 2
+	}
+	attributes := d.int16()
+	d.next(4 + 8 + 8 + 8 + 2 + 4)
+	count := d.int32()
+	data := d.buf
+	if attributes&7 == kafkaCompressGzip {
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return 2
+		}
+		data, _ = ioutil.ReadAll(zr)
+	}
+
+	k.Lock()
+	defer k.Unlock()
+	r := kafkaDecoder{buf: data}
+	for ; count > 0; count-- {
+		r.varint() // length
+		r.int8()
+		r.varint()
+		r.varint()
+		if n := r.varint(); n >= 0 {
+			k.keys[partition] = append(k.keys[partition], string(r.next(int(n))))
+		} else {
+			k.keys[partition] = append(k.keys[partition], "")
+		}
+		value := r.next(int(r.varint()))
+		r.varint()
+		k.partitions[partition] = append(k.partitions[partition], string(value))
+	}
+	if r.err != nil {
+		return 2
+	}
+	return 0
+}
+
+func TestKafkaBuiltin(t *testing.T) {
+	broker := newFakeKafka(t)
+	defer broker.ln.Close()
+
+	log := NewLogger()
+	err := log.AddAdapter(AdapterKafka, LevelInfoStr, `{"brokers":["`+broker.ln.Addr().String()+
+		`"], "topic":"logs", "keyfield":"user", "compression":"gzip", "batchsize":3}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// "21" hash to partition 0, "abc" to partition 1
+	log.WithFields(Fields{"user": "21"}).Info("a")
+	log.WithFields(Fields{"user": "abc"}).Info("b")
+	log.WithFields(Fields{"user": "21"}).Info("c")
+	log.Close()
+
+	var messages [2]string
+	for partition, values := range broker.partitions {
+		for _, value := range values {
+			var record jsonRecord
+			_ = json.Unmarshal([]byte(value), &record)
+			messages[partition] += record.Message
+		}
+	}
+	if messages[0] != "ac" || messages[1] != "b" || broker.keys[1][0] != "abc" {
+		t.Error("wrong partitions:", broker.partitions, broker.keys)
+	}
+	if stats := log.DeliveryStats(); stats[0].Sent != 3 || stats[0].Batches != 1 {
+		t.Error("wrong stats:", stats)
+	}
+}
+
+func TestKafkaInvalid(t *testing.T) {
+	log := NewLogger()
+	base := `"brokers":["localhost:9092"], "topic":"logs"`
+	cases := map[string]string{
+		`{"topic":"logs"}`:                       "brokers",
+		`{"brokers":["localhost:9092"]}`:         "topic",
+		`{` + base + `, "acks":"2"}`:             "acks",
+		`{` + base + `, "format":"xml"}`:         "format",
+		`{` + base + `, "compression":"brotli"}`: "compression",
+		`{` + base + `, "compression":"zstd"}`:   "compression",
+		`{` + base + `, "client":"sarama"}`:      "client",
+		`{` + base + `, "buffersize":-1}`:        "buffersize",
+	}
+	for helper, field := range cases {
+		err := log.AddAdapter(AdapterKafka, LevelInfoStr, helper)
+		if cErr, ok := err.(*ConfigError); !ok || cErr.Field != field {
+			t.Error(helper, "not get ConfigError on", field, "but", err)
+		}
+	}
+}
@@ -1,13 +1,18 @@
 package logs
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"path"
 	"runtime"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -28,11 +33,27 @@ const (
 	LevelErrorStr   = "error"
 )
 
+var levelName = []string{LevelTraceStr, LevelDebugStr, LevelInfoStr, LevelWarningStr, LevelErrorStr}
+
 var levelString = []string{"  [trace]", "  [debug]", "   [info]", "[warning]", "  [error]"}
 
 const (
 	AdapterFile    = "file"
 	AdapterConsole = "console"
+	AdapterSyslog  = "syslog"
+	AdapterNet     = "net"
+	AdapterHTTP    = "http"
+	AdapterLoki    = "loki"
+
+	AdapterElasticsearch = "elasticsearch"
+	AdapterGELF          = "gelf"
+	AdapterJournald      = "journald"
+	AdapterMemory        = "memory"
+	AdapterSMTP          = "smtp"
+	AdapterWebhook       = "webhook"
+	AdapterKafka         = "kafka"
+
+	AdapterFingersCrossed = "fingerscrossed"
 )
 
 var NoSupportLevel = errors.New("not support log record level")
@@ -44,12 +65,16 @@ type traceStruct struct {
 	funcName string
 }
 
+// Fields are key-value pairs attached to records
+type Fields map[string]interface{}
+
 type logMessage struct {
 	time       time.Time
 	timeString string
 	level      int
 	message    string
 	trace      traceStruct
+	fields     Fields
 }
 
 type logWriter interface {
@@ -58,13 +83,84 @@ type logWriter interface {
 	Destroy()
 }
 
+// implemented by adapters which can rotate their output, like file
+type rotateWriter interface {
+	setRotateHook(fn func(RotateEvent))
+	forceRotate() error
+}
+
+// implemented by adapters which have errors happened out of WriteMsg
+type errorWriter interface {
+	setErrorHook(fn func(error))
+}
+
+// implemented by adapters wrapping another one, like fingerscrossed
+type wrapWriter interface {
+	unwrap() logWriter
+}
+
+// unwrapWriter return the innermost adapter wrapped by writer, or writer itself
+func unwrapWriter(writer logWriter) logWriter {
+	for {
+		ww, ok := writer.(wrapWriter)
+		if !ok {
+			return writer
+		}
+		writer = ww.unwrap()
+	}
+}
+
+// errorReporter is embedded by adapters to implement errorWriter
+type errorReporter struct {
+	hookLock  sync.RWMutex
+	errorHook func(error)
+}
+
+func (r *errorReporter) setErrorHook(fn func(error)) {
+	r.hookLock.Lock()
+	r.errorHook = fn
+	r.hookLock.Unlock()
+}
+
+// reportError send err to error hook in a new goroutine, hook may log with same logger.
+// Logger always set the hook, stderr is only for adapters used alone
+func (r *errorReporter) reportError(err error) {
+	r.hookLock.RLock()
+	hook := r.errorHook
+	r.hookLock.RUnlock()
+	if hook != nil {
+		go hook(err)
+	} else {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+	}
+}
+
 type Logger struct {
+	// first field to make sure 64-bit aligned
+	counter loggerCounter
+
 	recorder       []logWriter
 	recorderCount  int
 	logMsgCh       chan logMessage
 	logMsgChClosed bool
 	wg             sync.WaitGroup
 	asyncStart     bool
+	hookLock       sync.RWMutex
+	rotateHook     func(RotateEvent)
+	errorHook      func(error)
+	// counters of recorder with the same index
+	adapters []*adapterCounter
+
+	// logger made by WithFields write to parent's adapters
+	parent *Logger
+	fields Fields
+
+	sampler      *sampler
+	dedup        *dedup
+	extractors   []ContextExtractor
+	contextHooks []ContextHook
+	// set by WithContext for context hooks
+	ctx context.Context
 }
 
 func NewLoggerWithCmdWriter(level string) *Logger {
@@ -94,11 +190,33 @@ func NewLogger() *Logger {
 }
 
 func (logger *Logger) AddAdapter(adapterName string, level string, helper string) (err error) {
+	oneWriter, err := newAdapter(adapterName, level, helper)
+	if err != nil {
+		return
+	}
+	c := &adapterCounter{adapter: adapterName}
+	if rw, ok := oneWriter.(rotateWriter); ok {
+		rw.setRotateHook(logger.adapterRotateHook(c))
+	}
+	if ew, ok := oneWriter.(errorWriter); ok {
+		ew.setErrorHook(logger.adapterErrorHook(c))
+	}
+	logger.recorder = append(logger.recorder, oneWriter)
+	logger.adapters = append(logger.adapters, c)
+	logger.recorderCount++
+	return
+}
+
+// newAdapter create adapter by name, also used by adapters wrapping another one
+func newAdapter(adapterName string, level string, helper string) (oneWriter logWriter, err error) {
 	if helper == "" {
 		helper = `{}`
 	}
+	helper, rateLimit, err := splitRateLimit(adapterName, helper)
+	if err != nil {
+		return
+	}
 
-	var oneWriter logWriter
 	switch adapterName {
 	case AdapterConsole:
 		oneWriter, err = newConsoleAdapter(level, helper)
@@ -106,20 +224,134 @@ func (logger *Logger) AddAdapter(adapterName string, level string, helper string
 	case AdapterFile:
 		oneWriter, err = newFileAdapter(level, helper)
 		break
+	case AdapterSyslog:
+		oneWriter, err = newSyslogAdapter(level, helper)
+		break
+	case AdapterNet:
+		oneWriter, err = newNetAdapter(level, helper)
+		break
+	case AdapterHTTP:
+		oneWriter, err = newHTTPAdapter(level, helper)
+		break
+	case AdapterLoki:
+		oneWriter, err = newLokiAdapter(level, helper)
+		break
+	case AdapterElasticsearch:
+		oneWriter, err = newElasticsearchAdapter(level, helper)
+		break
+	case AdapterGELF:
+		oneWriter, err = newGELFAdapter(level, helper)
+		break
+	case AdapterJournald:
+		oneWriter, err = newJournaldAdapter(level, helper)
+		break
+	case AdapterMemory:
+		oneWriter, err = newMemoryAdapter(level, helper)
+		break
+	case AdapterSMTP:
+		oneWriter, err = newSMTPAdapter(level, helper)
+		break
+	case AdapterWebhook:
+		oneWriter, err = newWebhookAdapter(level, helper)
+		break
+	case AdapterKafka:
+		oneWriter, err = newKafkaAdapter(level, helper)
+		break
+	case AdapterFingersCrossed:
+		oneWriter, err = newFingersCrossedAdapter(level, helper)
+		break
 
 	default:
 		err = NoSupportAdapter
 
 	}
+	if err != nil || rateLimit == nil {
+		return
+	}
+
+	limited, err := newRateLimitWriter(adapterName, level, oneWriter, rateLimit)
 	if err != nil {
+		oneWriter.Destroy()
+		oneWriter = nil
 		return
 	}
-	logger.recorder = append(logger.recorder, oneWriter)
-	logger.recorderCount++
+	oneWriter = limited
+	return
+}
+
+// OnRotate set fn to be called after any file adapter finish rotation,
+// fn run in a new goroutine so it can be slow
+func (logger *Logger) OnRotate(fn func(RotateEvent)) {
+	logger.hookLock.Lock()
+	logger.rotateHook = fn
+	logger.hookLock.Unlock()
+}
+
+// OnError set fn to receive errors of adapters, like write or rotation failure,
+// instead of printing them to stderr. Do not log with the same logger in fn
+// as write errors are reported synchronously
+func (logger *Logger) OnError(fn func(error)) {
+	logger.hookLock.Lock()
+	logger.errorHook = fn
+	logger.hookLock.Unlock()
+}
+
+func (logger *Logger) reportError(err error) {
+	logger.hookLock.RLock()
+	hook := logger.errorHook
+	logger.hookLock.RUnlock()
+	if hook != nil {
+		hook(err)
+	} else {
+		_, _ = fmt.Fprint(os.Stderr, err)
+	}
+}
+
+// adapterRotateHook count rotations of adapter, then call hook set by OnRotate
+func (logger *Logger) adapterRotateHook(c *adapterCounter) func(RotateEvent) {
+	return func(event RotateEvent) {
+		atomic.AddUint64(&c.rotations, 1)
+		logger.hookLock.RLock()
+		hook := logger.rotateHook
+		logger.hookLock.RUnlock()
+		if hook != nil {
+			hook(event)
+		}
+	}
+}
+
+// adapterErrorHook count errors reported by adapter, then call hook set by OnError.
+// Adapters call it in a new goroutine
+func (logger *Logger) adapterErrorHook(c *adapterCounter) func(error) {
+	return func(err error) {
+		atomic.AddUint64(&c.errors, 1)
+		logger.hookLock.RLock()
+		hook := logger.errorHook
+		logger.hookLock.RUnlock()
+		if hook != nil {
+			hook(err)
+		} else {
+			_, _ = fmt.Fprintln(os.Stderr, err)
+		}
+	}
+}
+
+// Rotate force all file adapter rotate now, return the first error
+func (logger *Logger) Rotate() (err error) {
+	for _, writer := range logger.recorder {
+		if rw, ok := writer.(rotateWriter); ok {
+			if rErr := rw.forceRotate(); rErr != nil && err == nil {
+				err = rErr
+			}
+		}
+	}
 	return
 }
 
 func (logger *Logger) Close() {
+	if logger.dedup != nil {
+		logger.SetDedup(0)
+	}
 	if logger.asyncStart {
 		if logger.logMsgChClosed == false {
 			close(logger.logMsgCh)
@@ -133,7 +365,44 @@ func (logger *Logger) Close() {
 	}
 }
 
+// WithFields return a logger which attach fields to every record, and write
+// to the adapters of logger. Adapters should be added to the origin logger
+func (logger *Logger) WithFields(fields Fields) *Logger {
+	merged := make(Fields, len(logger.fields)+len(fields))
+	for k, v := range logger.fields {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+
+	root := logger
+	if logger.parent != nil {
+		root = logger.parent
+	}
+	return &Logger{
+		logMsgChClosed: true,
+		parent:         root,
+		fields:         merged,
+	}
+}
+
 func (logger *Logger) writeMsg(message logMessage) {
+	if logger.parent != nil {
+		logger.parent.writeMsg(message)
+		return
+	}
+
+	atomic.AddUint64(&logger.counter.records[message.level], 1)
+	if logger.dedup != nil {
+		logger.dedup.write(logger, message)
+		return
+	}
+	logger.write(message)
+}
+
+// write message to adapters, must be called by root logger
+func (logger *Logger) write(message logMessage) {
 	if logger.recorderCount <= 0 {
 		_, _ = fmt.Fprint(os.Stderr, "no recorder in the logger\n")
 		return
@@ -142,10 +411,15 @@ func (logger *Logger) writeMsg(message logMessage) {
 	if logger.asyncStart {
 		logger.logMsgCh <- message
 	} else {
-		for _, writer := range logger.recorder {
-			if err := writer.WriteMsg(message); err != nil {
-				_, _ = fmt.Fprint(os.Stderr, err)
-			}
+		logger.writeAdapters(message)
+	}
+}
+
+func (logger *Logger) writeAdapters(message logMessage) {
+	for i, writer := range logger.recorder {
+		if err := writer.WriteMsg(message); err != nil {
+			atomic.AddUint64(&logger.adapters[i].errors, 1)
+			logger.reportError(err)
 		}
 	}
 }
@@ -171,11 +445,7 @@ func (logger *Logger) asyncWriteMsg() {
 					return
 				}
 
-				for _, writer := range logger.recorder {
-					if err := writer.WriteMsg(message); err != nil {
-						_, _ = fmt.Fprint(os.Stderr, err)
-					}
-				}
+				logger.writeAdapters(message)
 
 			}
 
@@ -202,6 +472,11 @@ func (logger *Logger) Error(message string, args ...interface{}) {
 }
 
 func (logger *Logger) saveLog(level int, msg string, args ...interface{}) {
+	ok, suppressed := logger.sample(level, msg)
+	if !ok {
+		return
+	}
+
 	singleLog := logMessage{}
 	singleLog.level = level
 
@@ -213,8 +488,13 @@ func (logger *Logger) saveLog(level int, msg string, args ...interface{}) {
 		singleLog.time.Nanosecond()/100000)
 
 	singleLog.message = parseMessage(msg, args...)
+	singleLog.fields = logger.fields
+	if suppressed > 0 {
+		singleLog.fields = withField(logger.fields, SuppressedField, suppressed)
+	}
 
 	logger.writeMsg(singleLog)
+	logger.runContextHooks(singleLog)
 }
 
 // use format to parse message
@@ -235,6 +515,11 @@ func (logger *Logger) ErrorF(message string, args ...interface{}) {
 }
 
 func (logger *Logger) saveLogFormat(level int, msg string, args ...interface{}) {
+	ok, suppressed := logger.sample(level, msg)
+	if !ok {
+		return
+	}
+
 	singleLog := logMessage{}
 	singleLog.level = level
 
@@ -246,8 +531,13 @@ func (logger *Logger) saveLogFormat(level int, msg string, args ...interface{})
 		singleLog.time.Nanosecond()/100000)
 
 	singleLog.message = fmt.Sprintf(msg, args...)
+	singleLog.fields = logger.fields
+	if suppressed > 0 {
+		singleLog.fields = withField(logger.fields, SuppressedField, suppressed)
+	}
 
 	logger.writeMsg(singleLog)
+	logger.runContextHooks(singleLog)
 }
 
 func parseMessage(message string, args ...interface{}) string {
@@ -270,6 +560,111 @@ func parseMessage(message string, args ...interface{}) string {
 
 }
 
+// textMessage format message as one line, caller is only shown when writer level is trace
+func textMessage(writerLevel int, message logMessage) string {
+	var msg string
+	if writerLevel == LevelTrace {
+		msg = fmt.Sprintf("%s %s [%s] [%s:%d] - %s", message.timeString, levelString[message.level],
+			message.trace.funcName, message.trace.file, message.trace.line, message.message)
+	} else {
+		msg = fmt.Sprintf("%s %s - %s", message.timeString, levelString[message.level], message.message)
+	}
+	if len(message.fields) > 0 {
+		msg += " " + fieldsString(message.fields)
+	}
+	return msg + "\n"
+}
+
+// jsonRecord is the json form of record
+type jsonRecord struct {
+	Time    string `json:"time,omitempty"`
+	Level   string `json:"level"`
+	Message string `json:"message"`
+	File    string `json:"file,omitempty"`
+	Line    int    `json:"line,omitempty"`
+	Func    string `json:"func,omitempty"`
+	Fields  Fields `json:"fields,omitempty"`
+}
+
+// newJSONRecord convert message to jsonRecord, caller is only set when writer level is trace
+func newJSONRecord(writerLevel int, message logMessage) jsonRecord {
+	record := jsonRecord{
+		Time:    message.time.Format(time.RFC3339Nano),
+		Level:   levelName[message.level],
+		Message: message.message,
+		Fields:  jsonFields(message.fields),
+	}
+	if writerLevel == LevelTrace {
+		record.File = message.trace.file
+		record.Line = message.trace.line
+		record.Func = message.trace.funcName
+	}
+	return record
+}
+
+// jsonMessage format message as one line json
+func jsonMessage(writerLevel int, message logMessage) []byte {
+	record := newJSONRecord(writerLevel, message)
+	return marshalJSONRecord(&record, &record)
+}
+
+// marshalJSONRecord marshal v which contain record,
+// if some field of record can not be json, use string of them
+func marshalJSONRecord(v interface{}, record *jsonRecord) []byte {
+	data, err := json.Marshal(v)
+	if err != nil {
+		fields := make(Fields, len(record.Fields))
+		for k, f := range record.Fields {
+			fields[k] = fmt.Sprintf("%+v", f)
+		}
+		record.Fields = fields
+		data, _ = json.Marshal(v)
+	}
+	return data
+}
+
+// jsonFields replace error in fields by its message, as error is always {} in json
+func jsonFields(fields Fields) Fields {
+	if len(fields) == 0 {
+		return nil
+	}
+	result := make(Fields, len(fields))
+	for k, v := range fields {
+		if err, ok := v.(error); ok {
+			v = err.Error()
+		}
+		result[k] = v
+	}
+	return result
+}
+
+// fieldsString format fields as key=value sorted by key
+func fieldsString(fields Fields) string {
+	keys := sortedFieldKeys(fields)
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, k+"="+fieldValueString(fields[k]))
+	}
+	return strings.Join(pairs, " ")
+}
+
+func sortedFieldKeys(fields Fields) []string {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func fieldValueString(value interface{}) string {
+	v := fmt.Sprintf("%+v", value)
+	if v == "" || strings.ContainsAny(v, " =\"\n") {
+		return strconv.Quote(v)
+	}
+	return v
+}
+
 func logTracer() (t traceStruct) {
 
 	var (
@@ -1,8 +1,10 @@
 package logs
 
 import (
+	"io/ioutil"
 	"os"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 )
@@ -170,3 +172,20 @@ func BenchmarkMultipleWriterAsync(b *testing.B) {
 	log.Close()
 	_ = os.RemoveAll("./async-bench")
 }
+
+func TestWithFields(t *testing.T) {
+	log := NewLogger()
+	_ = log.AddAdapter("file", LevelInfoStr, `{"filename":"fields.log", "rotate":false}`)
+
+	log.WithFields(Fields{"user": "box jan", "id": 1}).WithFields(Fields{"id": 2}).Info("login")
+	log.Close()
+
+	data, err := ioutil.ReadFile("fields.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := ` - login id=2 user="box jan"` + "\n"; !strings.HasSuffix(string(data), expected) {
+		t.Error(string(data), "not end with", expected)
+	}
+	_ = os.Remove("fields.log")
+}
@@ -0,0 +1,303 @@
+package logs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const lokiPushPath = "/loki/api/v1/push"
+
+const (
+	LokiFormatText = "text"
+	LokiFormatJSON = "json"
+)
+
+type lokiWriter struct {
+	// first field to make sure 64-bit aligned
+	counter deliveryCounter
+
+	level int
+
+	// Loki address like "http://localhost:3100", push path is added if no path
+	URL      string            `json:"url"`
+	Tenant   string            `json:"tenant"`
+	Username string            `json:"username"`
+	Password string            `json:"password"`
+	Headers  map[string]string `json:"headers"`
+	Gzip     bool              `json:"gzip"`
+	Timeout  Duration          `json:"timeout"`
+	tlsOptions
+
+	// Labels of every stream
+	Labels map[string]string `json:"labels"`
+	// Label name of record level, empty means not a label
+	LevelLabel string `json:"levellabel"`
+	// Record fields used as labels, they are removed from the line
+	FieldLabels []string `json:"fieldlabels"`
+	// Line format, text or json
+	Format string `json:"format"`
+
+	// Retry on 429, 5xx and network error
+	batchOptions
+
+	pushURL string
+	client  *http.Client
+	batcher *batcher
+	errorReporter
+}
+
+// lokiStream is one stream of push request
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+
+	// unix nano of values, to sort values
+	times []int64
+}
+
+func (s *lokiStream) Len() int {
+	return len(s.Values)
+}
+
+func (s *lokiStream) Less(i, j int) bool {
+	return s.times[i] < s.times[j]
+}
+
+func (s *lokiStream) Swap(i, j int) {
+	s.Values[i], s.Values[j] = s.Values[j], s.Values[i]
+	s.times[i], s.times[j] = s.times[j], s.times[i]
+}
+
+func (w *lokiWriter) WriteMsg(message logMessage) error {
+	if message.level < w.level {
+		return nil
+	}
+
+	line := w.line(message)
+	err := w.batcher.add(batchRecord{message: message, data: []byte(line)})
+	if err != nil {
+		atomic.AddUint64(&w.counter.dropped, 1)
+	}
+	return err
+}
+
+func (w *lokiWriter) Flush() {
+	w.batcher.flush()
+}
+
+func (w *lokiWriter) Destroy() {
+	w.batcher.close()
+}
+
+func (w *lokiWriter) reportError(err error) {
+	w.counter.setError(err)
+	w.errorReporter.reportError(err)
+}
+
+func (w *lokiWriter) deliveryStats() DeliveryStats {
+	return w.counter.stats(AdapterLoki)
+}
+
+// line format message without the fields used as labels
+func (w *lokiWriter) line(message logMessage) string {
+	if len(w.FieldLabels) > 0 && len(message.fields) > 0 {
+		fields := make(Fields, len(message.fields))
+		for k, v := range message.fields {
+			fields[k] = v
+		}
+		for _, k := range w.FieldLabels {
+			delete(fields, k)
+		}
+		message.fields = fields
+	}
+
+	if w.Format == LokiFormatJSON {
+		return string(jsonMessage(w.level, message))
+	}
+	return strings.TrimSuffix(textMessage(w.level, message), "\n")
+}
+
+// labels return labels of the stream message belongs to
+func (w *lokiWriter) labels(message logMessage) map[string]string {
+	labels := make(map[string]string, len(w.Labels)+len(w.FieldLabels)+1)
+	for k, v := range w.Labels {
+		labels[k] = v
+	}
+	if w.LevelLabel != "" {
+		labels[w.LevelLabel] = levelName[message.level]
+	}
+	for _, k := range w.FieldLabels {
+		if v, ok := message.fields[k]; ok {
+			labels[lokiLabelName(k)] = fmt.Sprintf("%+v", v)
+		}
+	}
+	return labels
+}
+
+// lokiLabelName replace chars not allowed in label name by '_'
+func lokiLabelName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, name)
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "_" + name
+	}
+	return name
+}
+
+func lokiStreamKey(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString(strconv.Quote(k) + "=" + strconv.Quote(labels[k]) + ",")
+	}
+	return b.String()
+}
+
+// streams group batch by labels, values of every stream are ordered by time
+func (w *lokiWriter) streams(batch []batchRecord) []*lokiStream {
+	var streams []*lokiStream
+	index := make(map[string]*lokiStream)
+
+	for _, record := range batch {
+		labels := w.labels(record.message)
+		key := lokiStreamKey(labels)
+		stream, ok := index[key]
+		if !ok {
+			stream = &lokiStream{Stream: labels}
+			index[key] = stream
+			streams = append(streams, stream)
+		}
+		ts := record.message.time.UnixNano()
+		stream.Values = append(stream.Values, [2]string{strconv.FormatInt(ts, 10), string(record.data)})
+		stream.times = append(stream.times, ts)
+	}
+
+	for _, stream := range streams {
+		sort.Stable(stream)
+	}
+	return streams
+}
+
+// send is called by batcher in its goroutine
+func (w *lokiWriter) send(batch []batchRecord) {
+	body, err := json.Marshal(map[string]interface{}{"streams": w.streams(batch)})
+	if err == nil && w.Gzip {
+		body, err = gzipBytes(body)
+	}
+	if err == nil {
+		err = w.retry(&w.counter, func() (bool, error) {
+			return w.push(body)
+		})
+	}
+
+	if err != nil {
+		atomic.AddUint64(&w.counter.failed, uint64(len(batch)))
+		w.reportError(err)
+		return
+	}
+	atomic.AddUint64(&w.counter.sent, uint64(len(batch)))
+	atomic.AddUint64(&w.counter.batches, 1)
+}
+
+func (w *lokiWriter) push(body []byte) (retryable bool, err error) {
+	req, err := http.NewRequest(http.MethodPost, w.pushURL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if w.Gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	if w.Tenant != "" {
+		req.Header.Set("X-Scope-OrgID", w.Tenant)
+	}
+	if w.Username != "" {
+		req.SetBasicAuth(w.Username, w.Password)
+	}
+	setHTTPHeaders(req, w.Headers)
+
+	_, retryable, err = doHTTP(w.client, req)
+	return
+}
+
+func newLokiAdapter(level string, helper string) (writer *lokiWriter, err error) {
+	w := getLokiWriter()
+
+	if err = decodeHelper(AdapterLoki, helper, w); err != nil {
+		return
+	}
+
+	if w.level = getLevelInt(level); w.level == -1 {
+		err = NoSupportLevel
+		return
+	}
+
+	if err = w.validate(); err != nil {
+		return
+	}
+
+	tlsConfig, err := w.tlsOptions.tlsConfig(AdapterLoki)
+	if err != nil {
+		return
+	}
+	w.client = newHTTPClient(tlsConfig, time.Duration(w.Timeout))
+	w.batcher = w.newBatcher(w.send)
+
+	writer = w
+	return
+}
+
+func (w *lokiWriter) validate() error {
+	u, err := url.Parse(w.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return newConfigError(AdapterLoki, "url", "%q is not a http or https url", w.URL)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = lokiPushPath
+	}
+	w.pushURL = u.String()
+
+	for k := range w.Labels {
+		if lokiLabelName(k) != k {
+			return newConfigError(AdapterLoki, "labels", "%q is not a valid label name", k)
+		}
+	}
+	if w.LevelLabel != "" && lokiLabelName(w.LevelLabel) != w.LevelLabel {
+		return newConfigError(AdapterLoki, "levellabel", "%q is not a valid label name", w.LevelLabel)
+	}
+
+	switch w.Format {
+	case LokiFormatText, LokiFormatJSON:
+	default:
+		return newConfigError(AdapterLoki, "format", "unknown format %q", w.Format)
+	}
+	return w.batchOptions.validate(AdapterLoki)
+}
+
+func getLokiWriter() *lokiWriter {
+	return &lokiWriter{
+		Timeout:      Duration(10 * time.Second),
+		LevelLabel:   "level",
+		Format:       LokiFormatText,
+		batchOptions: defaultBatchOptions(),
+		level:        LevelInfo,
+	}
+}
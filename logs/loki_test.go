@@ -0,0 +1,139 @@
+package logs
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type lokiPush struct {
+	Streams []struct {
+		Stream map[string]string `json:"stream"`
+		Values [][2]string       `json:"values"`
+	} `json:"streams"`
+}
+
+// fakeLoki record push requests, response 429 for the first limited requests
+type fakeLoki struct {
+	sync.Mutex
+	pushes  []lokiPush
+	tenants []string
+	limited int
+}
+
+func (l *fakeLoki) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	l.Lock()
+	defer l.Unlock()
+
+	if r.URL.Path != lokiPushPath {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if l.limited > 0 {
+		l.limited--
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusTooManyRequests)
+		return
+	}
+
+	var push lokiPush
+	if err := json.NewDecoder(r.Body).Decode(&push); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	l.pushes = append(l.pushes, push)
+	l.tenants = append(l.tenants, r.Header.Get("X-Scope-OrgID"))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func TestLokiPush(t *testing.T) {
+	loki := &fakeLoki{limited: 1}
+	server := httptest.NewServer(loki)
+	defer server.Close()
+
+	log := NewLogger()
+	err := log.AddAdapter(AdapterLoki, LevelInfoStr, `{"url":"`+server.URL+`", "tenant":"team",
+		"labels":{"app":"test"}, "fieldlabels":["user.id"], "backoff":"1ms", "maxbackoff":"10ms"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	log.WithFields(Fields{"user.id": 7, "ip": "::1"}).Error("denied")
+	log.Info("info 1")
+	log.Info("info 2")
+	log.Close()
+
+	if len(loki.pushes) != 1 || loki.tenants[0] != "team" {
+		t.Fatal("wrong pushes:", loki.pushes, loki.tenants)
+	}
+	streams := loki.pushes[0].Streams
+	if len(streams) != 2 {
+		t.Fatal("wrong streams:", streams)
+	}
+
+	errorStream := streams[0]
+	if errorStream.Stream["app"] != "test" || errorStream.Stream["level"] != LevelErrorStr ||
+		errorStream.Stream["user_id"] != "7" {
+		t.Error("wrong labels:", errorStream.Stream)
+	}
+	if line := errorStream.Values[0][1]; !strings.HasSuffix(line, " - denied ip=::1") {
+		t.Error("label field not removed from line:", line)
+	}
+
+	infoStream := streams[1]
+	if len(infoStream.Values) != 2 || !strings.HasSuffix(infoStream.Values[1][1], "info 2") {
+		t.Error("wrong info stream:", infoStream.Values)
+	}
+
+	if stats := log.DeliveryStats(); stats[0].Sent != 3 || stats[0].Retries != 1 {
+		t.Error("wrong stats:", stats)
+	}
+}
+
+func TestLokiStreamOrder(t *testing.T) {
+	w, err := newLokiAdapter(LevelInfoStr, `{"url":"http://127.0.0.1:3100"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Destroy()
+
+	now := time.Now()
+	var batch []batchRecord
+	for _, offset := range []int{3, 1, 2} {
+		message := logMessage{time: now.Add(time.Duration(offset)), level: LevelInfo}
+		batch = append(batch, batchRecord{message: message, data: []byte{byte('0' + offset)}})
+	}
+
+	streams := w.streams(batch)
+	if len(streams) != 1 {
+		t.Fatal("wrong streams:", streams)
+	}
+	var lines string
+	for _, value := range streams[0].Values {
+		lines += value[1]
+	}
+	if lines != "123" {
+		t.Error("values not ordered:", lines)
+	}
+}
+
+func TestLokiInvalid(t *testing.T) {
+	log := NewLogger()
+	cases := map[string]string{
+		`{"url":"localhost:3100"}`:                              "url",
+		`{"url":"http://localhost:3100", "labels":{"a-b":"c"}}`: "labels",
+		`{"url":"http://localhost:3100", "levellabel":"lv l"}`:  "levellabel",
+		`{"url":"http://localhost:3100", "format":"logfmt"}`:    "format",
+		`{"url":"http://localhost:3100", "backoff":"-1s"}`:      "backoff",
+	}
+	for helper, field := range cases {
+		err := log.AddAdapter(AdapterLoki, LevelInfoStr, helper)
+		if cErr, ok := err.(*ConfigError); !ok || cErr.Field != field {
+			t.Error(helper, "not get ConfigError on", field, "but", err)
+		}
+	}
+}
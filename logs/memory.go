@@ -0,0 +1,310 @@
+package logs
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryRecord is one record kept by memory adapter
+type MemoryRecord struct {
+	Time    time.Time `json:"time"`
+	Level   string    `json:"level"`
+	Message string    `json:"message"`
+	File    string    `json:"file,omitempty"`
+	Line    int       `json:"line,omitempty"`
+	Func    string    `json:"func,omitempty"`
+	Fields  Fields    `json:"fields,omitempty"`
+}
+
+// MemoryFilter select records from MemoryBuffer, zero value match all
+type MemoryFilter struct {
+	// Min level name, like LevelWarningStr
+	Level string
+	Since time.Time
+	Until time.Time
+	// Message or fields contain it
+	Contains string
+	// Only the last Limit records are returned if Limit > 0
+	Limit int
+}
+
+// MemoryBuffer keep the last records in a ring buffer, it is the memory adapter.
+// Get it by Logger.MemoryBuffer with the name in helper
+type MemoryBuffer struct {
+	level int
+
+	// Name to find the buffer
+	Name string `json:"name"`
+	// Max records kept
+	Records int `json:"records"`
+	// Max bytes of message and fields kept, zero means no limit
+	Bytes ByteSize `json:"bytes"`
+
+	lock  sync.RWMutex
+	ring  []logMessage
+	sizes []int
+	// index of oldest record and count of records
+	head  int
+	count int
+	bytes int64
+
+	subLock     sync.Mutex
+	subscribers map[chan MemoryRecord]struct{}
+}
+
+// MemoryBuffer return the memory adapter added with the name, nil if not found
+func (logger *Logger) MemoryBuffer(name string) *MemoryBuffer {
+	if logger.parent != nil {
+		return logger.parent.MemoryBuffer(name)
+	}
+	for _, writer := range logger.recorder {
+		if b, ok := unwrapWriter(writer).(*MemoryBuffer); ok && b.Name == name {
+			return b
+		}
+	}
+	return nil
+}
+
+func (b *MemoryBuffer) WriteMsg(message logMessage) error {
+	if message.level < b.level {
+		return nil
+	}
+
+	size := len(message.message)
+	if len(message.fields) > 0 {
+		size += len(fieldsString(message.fields))
+	}
+
+	b.lock.Lock()
+	if b.count == len(b.ring) {
+		b.evict()
+	}
+	i := (b.head + b.count) % len(b.ring)
+	b.ring[i], b.sizes[i] = message, size
+	b.count++
+	b.bytes += int64(size)
+	for b.Bytes > 0 && b.bytes > int64(b.Bytes) && b.count > 1 {
+		b.evict()
+	}
+	b.lock.Unlock()
+
+	b.publish(message)
+	return nil
+}
+
+// evict drop the oldest record, must hold lock
+func (b *MemoryBuffer) evict() {
+	b.bytes -= int64(b.sizes[b.head])
+	b.ring[b.head] = logMessage{}
+	b.head = (b.head + 1) % len(b.ring)
+	b.count--
+}
+
+func (b *MemoryBuffer) Flush() {
+}
+
+// Destroy close all subscribed channels
+func (b *MemoryBuffer) Destroy() {
+	b.subLock.Lock()
+	for ch := range b.subscribers {
+		close(ch)
+	}
+	b.subscribers = nil
+	b.subLock.Unlock()
+}
+
+// Snapshot return all records kept, oldest first
+func (b *MemoryBuffer) Snapshot() []MemoryRecord {
+	return b.Query(MemoryFilter{})
+}
+
+// Query return records match filter, oldest first
+func (b *MemoryBuffer) Query(filter MemoryFilter) (records []MemoryRecord) {
+	for _, message := range b.query(filter) {
+		records = append(records, b.record(message))
+	}
+	return
+}
+
+func (b *MemoryBuffer) query(filter MemoryFilter) (messages []logMessage) {
+	level := 0
+	if filter.Level != "" {
+		if level = getLevelInt(filter.Level); level == -1 {
+			return nil
+		}
+	}
+
+	b.lock.RLock()
+	all := make([]logMessage, 0, b.count)
+	for i := 0; i < b.count; i++ {
+		all = append(all, b.ring[(b.head+i)%len(b.ring)])
+	}
+	b.lock.RUnlock()
+
+	for _, message := range all {
+		if message.level < level ||
+			(!filter.Since.IsZero() && message.time.Before(filter.Since)) ||
+			(!filter.Until.IsZero() && message.time.After(filter.Until)) {
+			continue
+		}
+		if filter.Contains != "" && !strings.Contains(message.message, filter.Contains) &&
+			(len(message.fields) == 0 || !strings.Contains(fieldsString(message.fields), filter.Contains)) {
+			continue
+		}
+		messages = append(messages, message)
+	}
+
+	if filter.Limit > 0 && len(messages) > filter.Limit {
+		messages = messages[len(messages)-filter.Limit:]
+	}
+	return
+}
+
+// Subscribe return a channel receiving new records, records are dropped if the
+// channel is full. Call cancel to stop receiving, the channel is closed then
+func (b *MemoryBuffer) Subscribe(size int) (records <-chan MemoryRecord, cancel func()) {
+	ch := make(chan MemoryRecord, size)
+
+	b.subLock.Lock()
+	if b.subscribers == nil {
+		b.subscribers = make(map[chan MemoryRecord]struct{})
+	}
+	b.subscribers[ch] = struct{}{}
+	b.subLock.Unlock()
+
+	cancel = func() {
+		b.subLock.Lock()
+		if _, ok := b.subscribers[ch]; ok {
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+		b.subLock.Unlock()
+	}
+	return ch, cancel
+}
+
+func (b *MemoryBuffer) publish(message logMessage) {
+	b.subLock.Lock()
+	defer b.subLock.Unlock()
+	if len(b.subscribers) == 0 {
+		return
+	}
+
+	record := b.record(message)
+	for ch := range b.subscribers {
+		select {
+		case ch <- record:
+		default:
+		}
+	}
+}
+
+// ServeHTTP render records as text, or json array with "format=json".
+// Query parameters "level", "since", "until" (RFC 3339), "contains" and "limit" filter records
+func (b *MemoryBuffer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := MemoryFilter{Level: query.Get("level"), Contains: query.Get("contains")}
+
+	var err error
+	if s := query.Get("since"); s != "" {
+		if filter.Since, err = time.Parse(time.RFC3339Nano, s); err != nil {
+			http.Error(w, "invalid since: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if s := query.Get("until"); s != "" {
+		if filter.Until, err = time.Parse(time.RFC3339Nano, s); err != nil {
+			http.Error(w, "invalid until: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if s := query.Get("limit"); s != "" {
+		if filter.Limit, err = strconv.Atoi(s); err != nil {
+			http.Error(w, "invalid limit: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if filter.Level != "" && getLevelInt(filter.Level) == -1 {
+		http.Error(w, "invalid level: "+filter.Level, http.StatusBadRequest)
+		return
+	}
+
+	messages := b.query(filter)
+	if query.Get("format") == "json" {
+		// the same as json adapters, fields can not be json are converted to string
+		records := make([]json.RawMessage, 0, len(messages))
+		for _, message := range messages {
+			records = append(records, jsonMessage(b.level, message))
+		}
+		data, _ := json.Marshal(records)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(append(data, '\n'))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	for _, message := range messages {
+		_, _ = w.Write([]byte(textMessage(b.level, message)))
+	}
+}
+
+// record convert message to MemoryRecord, caller is only set when adapter level is trace.
+// Error in fields is replaced by its message as jsonMessage do
+func (b *MemoryBuffer) record(message logMessage) MemoryRecord {
+	record := MemoryRecord{
+		Time:    message.time,
+		Level:   levelName[message.level],
+		Message: message.message,
+		Fields:  jsonFields(message.fields),
+	}
+	if b.level == LevelTrace {
+		record.File = message.trace.file
+		record.Line = message.trace.line
+		record.Func = message.trace.funcName
+	}
+	return record
+}
+
+func newMemoryAdapter(level string, helper string) (writer *MemoryBuffer, err error) {
+	b := getMemoryBuffer()
+
+	if err = decodeHelper(AdapterMemory, helper, b); err != nil {
+		return
+	}
+
+	if b.level = getLevelInt(level); b.level == -1 {
+		err = NoSupportLevel
+		return
+	}
+
+	if err = b.validate(); err != nil {
+		return
+	}
+
+	b.ring = make([]logMessage, b.Records)
+	b.sizes = make([]int, b.Records)
+
+	writer = b
+	return
+}
+
+func (b *MemoryBuffer) validate() error {
+	if b.Records <= 0 {
+		return newConfigError(AdapterMemory, "records", "must be positive")
+	}
+	if b.Bytes < 0 {
+		return newConfigError(AdapterMemory, "bytes", "must not be negative")
+	}
+	return nil
+}
+
+func getMemoryBuffer() *MemoryBuffer {
+	return &MemoryBuffer{
+		Records: 1000,
+		level:   LevelInfo,
+	}
+}
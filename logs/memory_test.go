@@ -0,0 +1,171 @@
+package logs
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMemoryRing(t *testing.T) {
+	log := NewLogger()
+	if err := log.AddAdapter(AdapterMemory, LevelInfoStr, `{"name":"debug", "records":3}`); err != nil {
+		t.Fatal(err)
+	}
+	defer log.Close()
+
+	buffer := log.WithFields(Fields{"a": 1}).MemoryBuffer("debug")
+	if buffer == nil || log.MemoryBuffer("other") != nil {
+		t.Fatal("wrong buffer found")
+	}
+
+	for i := 0; i < 5; i++ {
+		log.Info("info " + strconv.Itoa(i))
+	}
+	records := buffer.Snapshot()
+	if len(records) != 3 || records[0].Message != "info 2" || records[2].Message != "info 4" {
+		t.Error("wrong records:", records)
+	}
+}
+
+func TestMemoryBytes(t *testing.T) {
+	buffer, err := newMemoryAdapter(LevelInfoStr, `{"bytes":"20B"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, message := range []string{"0123456789", "abcdefghij", "ABCDEFGHIJ"} {
+		_ = buffer.WriteMsg(logMessage{level: LevelInfo, message: message})
+	}
+	records := buffer.Snapshot()
+	if len(records) != 2 || records[0].Message != "abcdefghij" {
+		t.Error("wrong records:", records)
+	}
+}
+
+func TestMemoryQuery(t *testing.T) {
+	buffer, err := newMemoryAdapter(LevelTraceStr, `{}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	messages := []logMessage{
+		{time: now.Add(-time.Hour), level: LevelError, message: "old error"},
+		{time: now, level: LevelDebug, message: "debug"},
+		{time: now, level: LevelWarning, message: "warning", fields: Fields{"user": "box"}},
+		{time: now, level: LevelError, message: "error"},
+	}
+	for _, message := range messages {
+		_ = buffer.WriteMsg(message)
+	}
+
+	cases := []struct {
+		filter   MemoryFilter
+		expected string
+	}{
+		{MemoryFilter{Level: LevelWarningStr}, "old error,warning,error"},
+		{MemoryFilter{Since: now.Add(-time.Minute)}, "debug,warning,error"},
+		{MemoryFilter{Until: now.Add(-time.Minute)}, "old error"},
+		{MemoryFilter{Contains: "box"}, "warning"},
+		{MemoryFilter{Level: LevelWarningStr, Limit: 2}, "warning,error"},
+		{MemoryFilter{Level: "fatal"}, ""},
+	}
+	for _, c := range cases {
+		var got []string
+		for _, record := range buffer.Query(c.filter) {
+			got = append(got, record.Message)
+		}
+		if strings.Join(got, ",") != c.expected {
+			t.Error(c.filter, "got", got, "not", c.expected)
+		}
+	}
+}
+
+func TestMemorySubscribe(t *testing.T) {
+	buffer, err := newMemoryAdapter(LevelInfoStr, `{}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, cancel := buffer.Subscribe(1)
+	_ = buffer.WriteMsg(logMessage{level: LevelInfo, message: "first"})
+	// dropped as the channel is full
+	_ = buffer.WriteMsg(logMessage{level: LevelInfo, message: "second"})
+
+	if record := <-records; record.Message != "first" || record.Level != LevelInfoStr {
+		t.Error("wrong record:", record)
+	}
+	cancel()
+	if _, ok := <-records; ok {
+		t.Error("channel not closed")
+	}
+	cancel()
+	buffer.Destroy()
+}
+
+func TestMemoryHTTP(t *testing.T) {
+	log := NewLogger()
+	if err := log.AddAdapter(AdapterMemory, LevelInfoStr, `{}`); err != nil {
+		t.Fatal(err)
+	}
+	testFileCalls(log)
+	log.Close()
+	buffer := log.MemoryBuffer("")
+
+	rec := httptest.NewRecorder()
+	buffer.ServeHTTP(rec, httptest.NewRequest("GET", "/?level=warning", nil))
+	lines := strings.Split(strings.TrimSuffix(rec.Body.String(), "\n"), "\n")
+	if len(lines) != 2 || !strings.HasSuffix(lines[0], " - error") || !strings.HasSuffix(lines[1], " - warning") {
+		t.Error("wrong text:", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	buffer.ServeHTTP(rec, httptest.NewRequest("GET", "/?format=json&limit=1", nil))
+	var records []MemoryRecord
+	if err := json.Unmarshal(rec.Body.Bytes(), &records); err != nil || len(records) != 1 || records[0].Message != "info" {
+		t.Error("wrong json:", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	buffer.ServeHTTP(rec, httptest.NewRequest("GET", "/?since=yesterday", nil))
+	if rec.Code != 400 {
+		t.Error("invalid since not rejected")
+	}
+}
+
+func TestMemoryHTTPFields(t *testing.T) {
+	buffer, err := newMemoryAdapter(LevelInfoStr, `{}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = buffer.WriteMsg(logMessage{level: LevelInfo, message: "failed", fields: Fields{"err": errors.New("timeout")}})
+	_ = buffer.WriteMsg(logMessage{level: LevelInfo, message: "chan", fields: Fields{"ch": make(chan int), "n": 1}})
+
+	rec := httptest.NewRecorder()
+	buffer.ServeHTTP(rec, httptest.NewRequest("GET", "/?format=json", nil))
+	var records []MemoryRecord
+	if err := json.Unmarshal(rec.Body.Bytes(), &records); err != nil || len(records) != 2 {
+		t.Fatal("wrong json:", rec.Body.String())
+	}
+	if records[0].Fields["err"] != "timeout" {
+		t.Error("error field not its message:", records[0].Fields)
+	}
+	if ch, ok := records[1].Fields["ch"].(string); !ok || !strings.HasPrefix(ch, "0x") || records[1].Fields["n"] != "1" {
+		t.Error("field can not be json not converted to string:", records[1].Fields)
+	}
+}
+
+func TestMemoryInvalid(t *testing.T) {
+	log := NewLogger()
+	cases := map[string]string{
+		`{"records":0}`:  "records",
+		`{"bytes":"-1"}`: "bytes",
+	}
+	for helper, field := range cases {
+		err := log.AddAdapter(AdapterMemory, LevelInfoStr, helper)
+		if cErr, ok := err.(*ConfigError); !ok || cErr.Field != field {
+			t.Error(helper, "not get ConfigError on", field, "but", err)
+		}
+	}
+}
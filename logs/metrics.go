@@ -0,0 +1,200 @@
+package logs
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// Stats is a snapshot of counters of logger and its adapters
+type Stats struct {
+	// Records logged of every level name, including ones collapsed by dedup
+	Records map[string]uint64
+	// Records suppressed by sampling of every level name
+	Suppressed map[string]uint64
+	// Records waiting in async queue, zero if not async
+	QueueLength   int
+	QueueCapacity int
+	// In the order adapters added
+	Adapters []AdapterStats
+}
+
+// AdapterStats is a snapshot of counters of one adapter
+type AdapterStats struct {
+	Adapter string
+	// Errors returned by WriteMsg or reported later, like write or rotation failure
+	Errors    uint64
+	Rotations uint64
+	// Nil if adapter does not deliver records to remote
+	Delivery *DeliveryStats
+	// Nil if adapter has no "ratelimit" option
+	RateLimit *RateLimitStats
+}
+
+type loggerCounter struct {
+	records [LevelError + 1]uint64
+}
+
+type adapterCounter struct {
+	errors    uint64
+	rotations uint64
+	adapter   string
+}
+
+// Stats return counters of logger, loggers made by WithFields return the origin one's
+func (logger *Logger) Stats() Stats {
+	if logger.parent != nil {
+		return logger.parent.Stats()
+	}
+
+	stats := Stats{
+		Records:    make(map[string]uint64, len(levelName)),
+		Suppressed: make(map[string]uint64, len(levelName)),
+	}
+	for level, name := range levelName {
+		stats.Records[name] = atomic.LoadUint64(&logger.counter.records[level])
+		stats.Suppressed[name] = 0
+		if logger.sampler != nil {
+			stats.Suppressed[name] = atomic.LoadUint64(&logger.sampler.suppressed[level])
+		}
+	}
+	if logger.asyncStart {
+		stats.QueueLength, stats.QueueCapacity = len(logger.logMsgCh), cap(logger.logMsgCh)
+	}
+
+	for i, writer := range logger.recorder {
+		c := logger.adapters[i]
+		adapter := AdapterStats{
+			Adapter:   c.adapter,
+			Errors:    atomic.LoadUint64(&c.errors),
+			Rotations: atomic.LoadUint64(&c.rotations),
+		}
+		if dw, ok := unwrapWriter(writer).(deliveryWriter); ok {
+			delivery := dw.deliveryStats()
+			adapter.Delivery = &delivery
+		}
+		if rw := findRateLimit(writer); rw != nil {
+			rateLimit := rw.stats()
+			adapter.RateLimit = &rateLimit
+		}
+		stats.Adapters = append(stats.Adapters, adapter)
+	}
+	return stats
+}
+
+// MetricsHandler serve Stats of loggers in Prometheus text exposition format,
+// the key of loggers is used as "logger" label
+func MetricsHandler(loggers map[string]*Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_, _ = w.Write(metricsText(loggers))
+	})
+}
+
+// metricFamily is samples of one metric, written together as Prometheus requires
+type metricFamily struct {
+	name    string
+	help    string
+	gauge   bool
+	samples []string
+}
+
+func (f *metricFamily) add(value interface{}, labels ...string) {
+	var b strings.Builder
+	b.WriteString(f.name)
+	b.WriteByte('{')
+	for i := 0; i+1 < len(labels); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(labels[i] + `="` + escapeLabel(labels[i+1]) + `"`)
+	}
+	b.WriteString("} ")
+	b.WriteString(fmt.Sprint(value))
+	f.samples = append(f.samples, b.String())
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+func metricsText(loggers map[string]*Logger) []byte {
+	families := []*metricFamily{
+		{name: "logs_records_total", help: "Records logged by level."},
+		{name: "logs_sampling_suppressed_total", help: "Records suppressed by sampling by level."},
+		{name: "logs_queue_length", help: "Records waiting in async queue.", gauge: true},
+		{name: "logs_queue_capacity", help: "Capacity of async queue.", gauge: true},
+		{name: "logs_adapter_errors_total", help: "Write and other errors of adapter."},
+		{name: "logs_adapter_rotations_total", help: "Rotations of adapter output."},
+		{name: "logs_adapter_sent_total", help: "Records delivered to remote."},
+		{name: "logs_adapter_batches_total", help: "Batches delivered to remote."},
+		{name: "logs_adapter_retries_total", help: "Retries of delivery."},
+		{name: "logs_adapter_failed_total", help: "Records failed after all retries."},
+		{name: "logs_adapter_dropped_total", help: "Records dropped as queue is full."},
+		{name: "logs_adapter_spooled_total", help: "Records spooled after all retries failed."},
+		{name: "logs_adapter_ratelimit_passed_total", help: "Records passed rate limit."},
+		{name: "logs_adapter_ratelimit_dropped_total", help: "Records over rate limit and dropped."},
+		{name: "logs_adapter_ratelimit_diverted_total", help: "Records over rate limit and written to fallback adapter."},
+	}
+	records, suppressed, queueLength, queueCapacity := families[0], families[1], families[2], families[3]
+	errors, rotations := families[4], families[5]
+	sent, batches, retries, failed, dropped, spooled := families[6], families[7], families[8], families[9], families[10], families[11]
+	passed, limited, diverted := families[12], families[13], families[14]
+
+	names := make([]string, 0, len(loggers))
+	for name := range loggers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		stats := loggers[name].Stats()
+		for _, level := range levelName {
+			records.add(stats.Records[level], "logger", name, "level", level)
+			suppressed.add(stats.Suppressed[level], "logger", name, "level", level)
+		}
+		queueLength.add(stats.QueueLength, "logger", name)
+		queueCapacity.add(stats.QueueCapacity, "logger", name)
+
+		for i, adapter := range stats.Adapters {
+			labels := []string{"logger", name, "adapter", adapter.Adapter, "index", strconv.Itoa(i)}
+			errors.add(adapter.Errors, labels...)
+			rotations.add(adapter.Rotations, labels...)
+			if d := adapter.Delivery; d != nil {
+				sent.add(d.Sent, labels...)
+				batches.add(d.Batches, labels...)
+				retries.add(d.Retries, labels...)
+				failed.add(d.Failed, labels...)
+				dropped.add(d.Dropped, labels...)
+				spooled.add(d.Spooled, labels...)
+			}
+			if l := adapter.RateLimit; l != nil {
+				passed.add(l.Passed, labels...)
+				limited.add(l.Dropped, labels...)
+				diverted.add(l.Diverted, labels...)
+			}
+		}
+	}
+
+	var b bytes.Buffer
+	for _, f := range families {
+		if len(f.samples) == 0 {
+			continue
+		}
+		kind := "counter"
+		if f.gauge {
+			kind = "gauge"
+		}
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", f.name, f.help, f.name, kind)
+		for _, sample := range f.samples {
+			b.WriteString(sample + "\n")
+		}
+	}
+	return b.Bytes()
+}
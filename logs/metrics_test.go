@@ -0,0 +1,106 @@
+package logs
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+type failWriter struct{}
+
+func (failWriter) WriteMsg(message logMessage) error {
+	return errors.New("write failed")
+}
+
+func (failWriter) Flush() {}
+
+func (failWriter) Destroy() {}
+
+func TestStats(t *testing.T) {
+	collector := &httpCollector{}
+	server := httptest.NewServer(collector)
+	defer server.Close()
+
+	log := NewLogger()
+	log.OnError(func(err error) {})
+	_ = log.AddAdapter(AdapterFile, LevelInfoStr, `{"filename":"./stats/stats.log", "rotate":false}`)
+	_ = log.AddAdapter(AdapterHTTP, LevelInfoStr, `{"url":"`+server.URL+`"}`)
+	_ = log.AddAdapter(AdapterMemory, LevelInfoStr, `{"ratelimit":{"rate":1, "burst":1}}`)
+	log.recorder = append(log.recorder, failWriter{})
+	log.adapters = append(log.adapters, &adapterCounter{adapter: "fail"})
+	log.recorderCount++
+	_ = log.SetSampling(LevelDebugStr, Sampling{First: 1, Interval: time.Hour})
+	defer os.RemoveAll("./stats/")
+
+	testFileCalls(log)
+	log.Debug("debug")
+	if err := log.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100 && log.Stats().Adapters[0].Rotations == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	log.Close()
+
+	stats := log.WithFields(Fields{"a": 1}).Stats()
+
+	if stats.Records[LevelErrorStr] != 1 || stats.Records[LevelDebugStr] != 1 || stats.Records[LevelTraceStr] != 0 {
+		t.Error("wrong records:", stats.Records)
+	}
+	if stats.Suppressed[LevelDebugStr] != 1 || stats.QueueCapacity != 0 {
+		t.Error("wrong suppressed or queue:", stats)
+	}
+	if len(stats.Adapters) != 4 {
+		t.Fatal("wrong adapters:", stats.Adapters)
+	}
+	file, http, memory, fail := stats.Adapters[0], stats.Adapters[1], stats.Adapters[2], stats.Adapters[3]
+	if file.Adapter != AdapterFile || file.Rotations != 1 || file.Delivery != nil || file.RateLimit != nil {
+		t.Error("wrong file stats:", file)
+	}
+	if http.Delivery == nil || http.Delivery.Sent != 3 {
+		t.Error("wrong http stats:", http.Delivery)
+	}
+	if memory.RateLimit == nil || memory.RateLimit.Passed != 1 || memory.RateLimit.Dropped != 2 {
+		t.Error("wrong memory stats:", memory.RateLimit)
+	}
+	if fail.Errors != 4 {
+		t.Error("wrong errors:", fail.Errors)
+	}
+}
+
+func TestMetricsHandler(t *testing.T) {
+	log := NewLogger()
+	_ = log.AddAdapter(AdapterMemory, LevelInfoStr, `{"ratelimit":{"rate":1}}`)
+	log.Async()
+	log.Info("info")
+	log.Close()
+
+	server := httptest.NewServer(MetricsHandler(map[string]*Logger{`app"1`: log}))
+	defer server.Close()
+	resp, err := server.Client().Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := ioutil.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	text := string(data)
+
+	for _, expected := range []string{
+		"# TYPE logs_records_total counter\n",
+		`logs_records_total{logger="app\"1",level="info"} 1` + "\n",
+		"# TYPE logs_queue_capacity gauge\n",
+		`logs_queue_capacity{logger="app\"1"} 128` + "\n",
+		`logs_adapter_ratelimit_passed_total{logger="app\"1",adapter="memory",index="0"} 1` + "\n",
+	} {
+		if !strings.Contains(text, expected) {
+			t.Errorf("%q not found in:\n%s", expected, text)
+		}
+	}
+	if strings.Contains(text, "logs_adapter_sent_total") {
+		t.Error("delivery metrics of memory adapter:\n", text)
+	}
+}
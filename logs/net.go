@@ -0,0 +1,262 @@
+package logs
+
+import (
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	NetFramingNewline = "newline"
+	// 4 bytes big endian length before every record
+	NetFramingLength = "length"
+)
+
+type netWriter struct {
+	sync.Mutex
+
+	level      int
+	conn       net.Conn
+	connClosed chan struct{}
+
+	// "tcp", "udp", "unix" or "unixgram"
+	Network string `json:"network"`
+	Address string `json:"address"`
+	TLS     bool   `json:"tls"`
+	tlsOptions
+	Framing string   `json:"framing"`
+	Timeout Duration `json:"timeout"`
+
+	// Reconnect delay, double after each failure until MaxBackoff
+	Backoff    Duration `json:"backoff"`
+	MaxBackoff Duration `json:"maxbackoff"`
+	retry      backoff
+
+	// Records are kept in spool file while endpoint is down, empty means drop them
+	Spool        string   `json:"spool"`
+	SpoolMaxSize ByteSize `json:"spoolmaxsize"`
+	spool        *spool
+
+	tlsConfig *tls.Config
+	// true when the endpoint is down, to report error once for one outage
+	down bool
+	errorReporter
+}
+
+func (w *netWriter) WriteMsg(message logMessage) (err error) {
+	if message.level < w.level {
+		return nil
+	}
+
+	record := strings.TrimSuffix(textMessage(w.level, message), "\n")
+
+	w.Lock()
+	defer w.Unlock()
+
+	// fail fast while the endpoint is down, not try the spool on every record
+	if err = w.connect(); err == nil {
+		if err = w.replaySpool(); err == nil {
+			if err = w.send(record); err == nil {
+				w.down = false
+				return
+			}
+		}
+	}
+
+	if !w.down {
+		w.down = true
+		w.reportError(err)
+	}
+	if w.spool == nil {
+		return
+	}
+	return w.spoolRecord(record)
+}
+
+func (w *netWriter) Flush() {
+	w.Lock()
+	if w.spool != nil {
+		w.spool.flush()
+	}
+	w.Unlock()
+}
+
+func (w *netWriter) Destroy() {
+	w.Lock()
+	w.closeConn()
+	if w.spool != nil {
+		w.spool.destroy()
+	}
+	w.Unlock()
+}
+
+// connect make sure there is a usable conn, obey the backoff
+func (w *netWriter) connect() (err error) {
+	if w.conn != nil && isClosed(w.connClosed) {
+		w.closeConn()
+	}
+	if w.conn != nil {
+		return nil
+	}
+	if !w.retry.ready() {
+		return fmt.Errorf("net adapter: %s is down, retry after %s", w.Address, w.retry.next.Format(time.RFC3339))
+	}
+
+	var config *tls.Config
+	if w.TLS {
+		config = w.tlsConfig
+	}
+	if w.conn, err = dialConn(w.Network, w.Address, time.Duration(w.Timeout), config); err != nil {
+		w.conn = nil
+		w.retry.fail()
+		return
+	}
+	w.retry.reset()
+
+	w.connClosed = nil
+	if isStreamNetwork(w.Network) {
+		w.connClosed = watchPeerClose(w.conn)
+	}
+	return nil
+}
+
+func (w *netWriter) closeConn() {
+	if w.conn != nil {
+		_ = w.conn.Close()
+		w.conn = nil
+	}
+}
+
+// send write one record, reconnect once if write failed
+func (w *netWriter) send(record string) (err error) {
+	data := w.frame(record)
+	for i := 0; i < 2; i++ {
+		if err = w.connect(); err != nil {
+			return
+		}
+		if w.Timeout > 0 {
+			_ = w.conn.SetWriteDeadline(time.Now().Add(time.Duration(w.Timeout)))
+		}
+		if _, err = w.conn.Write(data); err == nil {
+			return
+		}
+		w.closeConn()
+	}
+	w.retry.fail()
+	return
+}
+
+func (w *netWriter) frame(record string) []byte {
+	if !isStreamNetwork(w.Network) {
+		return []byte(record)
+	}
+	if w.Framing == NetFramingLength {
+		data := make([]byte, 4+len(record))
+		binary.BigEndian.PutUint32(data, uint32(len(record)))
+		copy(data[4:], record)
+		return data
+	}
+	return []byte(record + "\n")
+}
+
+// spoolRecord save record to spool as json string, so it is one line
+func (w *netWriter) spoolRecord(record string) error {
+	quoted, _ := json.Marshal(record)
+	return w.spool.add(string(quoted))
+}
+
+// replaySpool send all spooled records, spooled records must be sent first to keep order
+func (w *netWriter) replaySpool() error {
+	if w.spool == nil {
+		return nil
+	}
+	return w.spool.replay(1, func(records []string) error {
+		var record string
+		// skip broken record
+		if json.Unmarshal([]byte(records[0]), &record) != nil {
+			return nil
+		}
+		return w.send(record)
+	})
+}
+
+func newNetAdapter(level string, helper string) (writer *netWriter, err error) {
+	w := getNetWriter()
+
+	if err = decodeHelper(AdapterNet, helper, w); err != nil {
+		return
+	}
+
+	if w.level = getLevelInt(level); w.level == -1 {
+		err = NoSupportLevel
+		return
+	}
+
+	if err = w.validate(); err != nil {
+		return
+	}
+
+	if w.Spool != "" {
+		if w.spool, err = newSpool(w.Spool, w.SpoolMaxSize); err != nil {
+			return
+		}
+	}
+
+	w.retry = backoff{initial: time.Duration(w.Backoff), max: time.Duration(w.MaxBackoff)}
+	// the endpoint can be down at start only if records can be spooled
+	if err = w.connect(); err != nil && w.spool == nil {
+		return
+	}
+	err = nil
+
+	writer = w
+	return
+}
+
+func (w *netWriter) validate() (err error) {
+	switch w.Network {
+	case "tcp", "tcp4", "tcp6", "udp", "udp4", "udp6", "unix", "unixgram":
+	default:
+		return newConfigError(AdapterNet, "network", "unknown network %q", w.Network)
+	}
+	if w.Address == "" {
+		return newConfigError(AdapterNet, "address", "must not be empty")
+	}
+
+	switch w.Framing {
+	case NetFramingNewline, NetFramingLength:
+	default:
+		return newConfigError(AdapterNet, "framing", "unknown framing %q", w.Framing)
+	}
+
+	if w.Backoff <= 0 {
+		return newConfigError(AdapterNet, "backoff", "must be positive")
+	}
+	if w.MaxBackoff < w.Backoff {
+		return newConfigError(AdapterNet, "maxbackoff", "must not be less than backoff")
+	}
+
+	if w.TLS {
+		if !strings.HasPrefix(w.Network, "tcp") {
+			return newConfigError(AdapterNet, "tls", "only support tcp")
+		}
+		w.tlsConfig, err = w.tlsOptions.tlsConfig(AdapterNet)
+	}
+	return
+}
+
+func getNetWriter() *netWriter {
+	return &netWriter{
+		Network:    "tcp",
+		Framing:    NetFramingNewline,
+		Timeout:    Duration(5 * time.Second),
+		Backoff:    Duration(time.Second),
+		MaxBackoff: Duration(time.Minute),
+		level:      LevelInfo,
+	}
+}
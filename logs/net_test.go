@@ -0,0 +1,231 @@
+package logs
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// serveLines accept conns on ln and send every received line to ch
+func serveLines(ln net.Listener, ch chan string) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			s := bufio.NewScanner(conn)
+			for s.Scan() {
+				ch <- s.Text()
+			}
+			_ = conn.Close()
+		}()
+	}
+}
+
+func receiveLine(t *testing.T, ch chan string) string {
+	select {
+	case line := <-ch:
+		return line
+	case <-time.After(time.Second):
+		t.Fatal("no record received")
+	}
+	return ""
+}
+
+func TestNetNewline(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	received := make(chan string, 8)
+	go serveLines(ln, received)
+
+	log := NewLogger()
+	if err = log.AddAdapter(AdapterNet, LevelInfoStr, `{"address":"`+ln.Addr().String()+`"}`); err != nil {
+		t.Fatal(err)
+	}
+	testFileCalls(log)
+	log.Close()
+
+	for _, expected := range []string{"error", "warning", "info"} {
+		if line := receiveLine(t, received); !strings.HasSuffix(line, " - "+expected) {
+			t.Error(line, "not end with", expected)
+		}
+	}
+}
+
+func TestNetLengthFraming(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	received := make(chan string, 8)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			var size uint32
+			if binary.Read(conn, binary.BigEndian, &size) != nil {
+				return
+			}
+			record := make([]byte, size)
+			if _, err := io.ReadFull(conn, record); err != nil {
+				return
+			}
+			received <- string(record)
+		}
+	}()
+
+	log := NewLogger()
+	err = log.AddAdapter(AdapterNet, LevelInfoStr, `{"address":"`+ln.Addr().String()+`", "framing":"length"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log.Info("multi\nline")
+	log.Close()
+
+	if record := receiveLine(t, received); !strings.HasSuffix(record, " - multi\nline") {
+		t.Error("wrong record:", record)
+	}
+}
+
+func TestNetSpool(t *testing.T) {
+	// get a free port, nobody listen on it now
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := ln.Addr().String()
+	_ = ln.Close()
+
+	log := NewLogger()
+	errs := make(chan error, 8)
+	log.OnError(func(err error) {
+		errs <- err
+	})
+	err = log.AddAdapter(AdapterNet, LevelInfoStr,
+		`{"address":"`+address+`", "backoff":"10ms", "spool":"./spool/net.spool"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		log.Info("spooled {}", i)
+	}
+	select {
+	case <-errs:
+	case <-time.After(time.Second):
+		t.Error("endpoint down not reported")
+	}
+	if f, err := os.Stat("./spool/net.spool"); err != nil || f.Size() == 0 {
+		t.Fatal("records not spooled")
+	}
+
+	if ln, err = net.Listen("tcp", address); err != nil {
+		t.Skip("can not listen again:", err)
+	}
+	defer ln.Close()
+	received := make(chan string, 8)
+	go serveLines(ln, received)
+
+	time.Sleep(50 * time.Millisecond)
+	log.Info("online")
+	log.Close()
+
+	for i := 0; i < 3; i++ {
+		if line := receiveLine(t, received); !strings.HasSuffix(line, " - spooled "+strconv.Itoa(i)) {
+			t.Error(line, "not spooled", i)
+		}
+	}
+	if line := receiveLine(t, received); !strings.HasSuffix(line, " - online") {
+		t.Error(line, "not online")
+	}
+	if f, err := os.Stat("./spool/net.spool"); err != nil || f.Size() != 0 {
+		t.Error("spool not cleaned")
+	}
+
+	_ = os.RemoveAll("./spool/")
+}
+
+func TestSpoolOffset(t *testing.T) {
+	name := "./spool-offset/a.spool"
+	s, err := newSpool(name, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		_ = s.add(strconv.Itoa(i))
+	}
+	before, _ := os.Stat(name)
+
+	// send 3 records then fail, the spool must not be rewritten
+	var sent []string
+	down := errors.New("down")
+	send := func(records []string) error {
+		if len(sent) == 3 {
+			return down
+		}
+		sent = append(sent, records...)
+		return nil
+	}
+	if err = s.replay(1, send); err != down {
+		t.Error("wrong replay error:", err)
+	}
+	if after, _ := os.Stat(name); !os.SameFile(before, after) || after.Size() != before.Size() {
+		t.Error("spool rewritten by failed replay")
+	}
+	s.destroy()
+
+	// restart, continue from the saved offset
+	if s, err = newSpool(name, 0); err != nil {
+		t.Fatal(err)
+	}
+	sent = nil
+	if err = s.replay(4, func(records []string) error {
+		sent = append(sent, records...)
+		return nil
+	}); err != nil {
+		t.Error(err)
+	}
+	if strings.Join(sent, ",") != "3,4,5,6,7,8,9" {
+		t.Error("wrong records replayed:", sent)
+	}
+	if s.pending() {
+		t.Error("spool still pending")
+	}
+	s.destroy()
+
+	_ = os.RemoveAll("./spool-offset/")
+}
+
+func TestNetInvalid(t *testing.T) {
+	log := NewLogger()
+	cases := map[string]string{
+		`{"network":"ipx", "address":"x"}`:                   "network",
+		`{"network":"tcp"}`:                                  "address",
+		`{"address":"x", "framing":"xml"}`:                   "framing",
+		`{"address":"x", "backoff":"0s"}`:                    "backoff",
+		`{"address":"x", "backoff":"1m", "maxbackoff":"1s"}`: "maxbackoff",
+		`{"network":"udp", "address":"x", "tls":true}`:       "tls",
+	}
+	for helper, field := range cases {
+		err := log.AddAdapter(AdapterNet, LevelInfoStr, helper)
+		if cErr, ok := err.(*ConfigError); !ok || cErr.Field != field {
+			t.Error(helper, "not get ConfigError on", field, "but", err)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+module github.com/boxjan/golib/logs/otellogs
+
+go 1.21
+
+require (
+	github.com/boxjan/golib v0.1.0
+	go.opentelemetry.io/otel v1.28.0
+	go.opentelemetry.io/otel/sdk v1.28.0
+	go.opentelemetry.io/otel/trace v1.28.0
+)
+
+require (
+	github.com/go-logr/logr v1.4.2 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	go.opentelemetry.io/otel/metric v1.28.0 // indirect
+	golang.org/x/sys v0.21.0 // indirect
+)
+
+// Build against the logs in this repository during development, it is
+// ignored when this module is used as a dependency
+replace github.com/boxjan/golib => ../..
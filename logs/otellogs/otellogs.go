@@ -0,0 +1,106 @@
+// Package otellogs correlate records of logs with OpenTelemetry traces, it
+// is a separate module so logs itself does not depend on OpenTelemetry
+package otellogs
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/boxjan/golib/logs"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Fields attached by Extract, values are lower case hex as W3C trace context
+const (
+	TraceIDField    = "trace_id"
+	SpanIDField     = "span_id"
+	TraceFlagsField = "trace_flags"
+)
+
+// EventName is the name of span events added by SpanEvents
+const EventName = "log"
+
+var levels = map[string]int{
+	logs.LevelTraceStr:   logs.LevelTrace,
+	logs.LevelDebugStr:   logs.LevelDebug,
+	logs.LevelInfoStr:    logs.LevelInfo,
+	logs.LevelWarningStr: logs.LevelWarning,
+	logs.LevelErrorStr:   logs.LevelError,
+}
+
+// Install add Extract to logger, and SpanEvents of level if level is not empty
+func Install(logger *logs.Logger, level string) error {
+	logger.AddContextExtractor(Extract)
+	if level == "" {
+		return nil
+	}
+	hook, err := SpanEvents(level)
+	if err != nil {
+		return err
+	}
+	logger.AddContextHook(hook)
+	return nil
+}
+
+// Extract return trace id, span id and trace flags of the span in ctx,
+// it is a logs.ContextExtractor. Nil if ctx has no valid span
+func Extract(ctx context.Context) logs.Fields {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return nil
+	}
+	return logs.Fields{
+		TraceIDField:    sc.TraceID().String(),
+		SpanIDField:     sc.SpanID().String(),
+		TraceFlagsField: sc.TraceFlags().String(),
+	}
+}
+
+// SpanEvents return a logs.ContextHook add records at or above level as events
+// of the recording span in ctx, with message, level and fields as attributes
+func SpanEvents(level string) (logs.ContextHook, error) {
+	min, ok := levels[level]
+	if !ok {
+		return nil, logs.NoSupportLevel
+	}
+
+	return func(ctx context.Context, level string, message string, fields logs.Fields) {
+		if levels[level] < min {
+			return
+		}
+		span := trace.SpanFromContext(ctx)
+		if !span.IsRecording() {
+			return
+		}
+
+		attrs := make([]attribute.KeyValue, 0, len(fields)+2)
+		attrs = append(attrs, attribute.String("log.severity", level), attribute.String("log.message", message))
+		for k, v := range fields {
+			switch k {
+			case TraceIDField, SpanIDField, TraceFlagsField:
+				continue
+			}
+			attrs = append(attrs, fieldAttribute(k, v))
+		}
+		span.AddEvent(EventName, trace.WithAttributes(attrs...))
+	}, nil
+}
+
+func fieldAttribute(k string, v interface{}) attribute.KeyValue {
+	switch value := v.(type) {
+	case string:
+		return attribute.String(k, value)
+	case bool:
+		return attribute.Bool(k, value)
+	case int:
+		return attribute.Int(k, value)
+	case int64:
+		return attribute.Int64(k, value)
+	case float64:
+		return attribute.Float64(k, value)
+	case error:
+		return attribute.String(k, value.Error())
+	}
+	return attribute.String(k, fmt.Sprintf("%+v", v))
+}
@@ -0,0 +1,67 @@
+package otellogs
+
+import (
+	"context"
+	"testing"
+
+	"github.com/boxjan/golib/logs"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestInstall(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	ctx, span := provider.Tracer("test").Start(context.Background(), "request")
+
+	log := logs.NewLogger()
+	if err := log.AddAdapter(logs.AdapterMemory, logs.LevelInfoStr, `{"name":"otel"}`); err != nil {
+		t.Fatal(err)
+	}
+	defer log.Close()
+	if err := Install(log, logs.LevelWarningStr); err != nil {
+		t.Fatal(err)
+	}
+
+	log.WithFields(logs.Fields{"user": 1}).InfoCtx(ctx, "login")
+	log.ErrorCtx(ctx, "query failed: {}", "timeout")
+	log.InfoCtx(context.Background(), "no span")
+	span.End()
+
+	records := log.MemoryBuffer("otel").Snapshot()
+	if len(records) != 3 {
+		t.Fatal("wrong records:", records)
+	}
+	sc := span.SpanContext()
+	for _, record := range records[:2] {
+		if record.Fields[TraceIDField] != sc.TraceID().String() || record.Fields[SpanIDField] != sc.SpanID().String() ||
+			record.Fields[TraceFlagsField] != "01" {
+			t.Error("wrong trace fields:", record.Fields)
+		}
+	}
+	if len(records[2].Fields) != 0 {
+		t.Error("fields attached without span:", records[2].Fields)
+	}
+
+	ended := recorder.Ended()
+	if len(ended) != 1 {
+		t.Fatal("wrong spans:", ended)
+	}
+	events := ended[0].Events()
+	if len(events) != 1 || events[0].Name != EventName {
+		t.Fatal("wrong events:", events)
+	}
+	attrs := map[string]string{}
+	for _, attr := range events[0].Attributes {
+		attrs[string(attr.Key)] = attr.Value.Emit()
+	}
+	if attrs["log.severity"] != logs.LevelErrorStr || attrs["log.message"] != "query failed: timeout" || attrs[TraceIDField] != "" {
+		t.Error("wrong event attributes:", attrs)
+	}
+}
+
+func TestSpanEventsInvalid(t *testing.T) {
+	if _, err := SpanEvents("verbose"); err != logs.NoSupportLevel {
+		t.Error("unknown level accepted:", err)
+	}
+}
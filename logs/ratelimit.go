@@ -0,0 +1,233 @@
+package logs
+
+import (
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RateLimitStats count records of adapter limited by "ratelimit" option
+type RateLimitStats struct {
+	Adapter string
+	// Records written to the adapter
+	Passed uint64
+	// Records over the limit and dropped
+	Dropped uint64
+	// Records over the limit and written to the fallback adapter
+	Diverted uint64
+}
+
+// RateLimitStats return stats of every adapter with "ratelimit" option
+func (logger *Logger) RateLimitStats() (stats []RateLimitStats) {
+	for _, writer := range logger.recorder {
+		if rw := findRateLimit(writer); rw != nil {
+			stats = append(stats, rw.stats())
+		}
+	}
+	return
+}
+
+// rateLimitOptions is the "ratelimit" option accepted by every adapter
+type rateLimitOptions struct {
+	// Records per second
+	Rate float64 `json:"rate"`
+	// Max records written at once, zero means the same as rate
+	Burst int `json:"burst"`
+	// Adapter and its helper to write records over the limit, empty means drop them
+	Adapter string          `json:"adapter"`
+	Helper  json.RawMessage `json:"helper"`
+}
+
+// rateLimitWriter limit records written to the wrapped adapter by token bucket
+type rateLimitWriter struct {
+	// first fields to make sure 64-bit aligned
+	passed   uint64
+	dropped  uint64
+	diverted uint64
+
+	sync.Mutex
+
+	adapter  string
+	level    int
+	writer   logWriter
+	fallback logWriter
+
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func (w *rateLimitWriter) WriteMsg(message logMessage) error {
+	if message.level < w.level {
+		return nil
+	}
+
+	if w.take(time.Now()) {
+		atomic.AddUint64(&w.passed, 1)
+		return w.writer.WriteMsg(message)
+	}
+	if w.fallback == nil {
+		atomic.AddUint64(&w.dropped, 1)
+		return nil
+	}
+	atomic.AddUint64(&w.diverted, 1)
+	return w.fallback.WriteMsg(message)
+}
+
+// take report whether a token is taken from bucket
+func (w *rateLimitWriter) take(now time.Time) bool {
+	w.Lock()
+	defer w.Unlock()
+
+	if elapsed := now.Sub(w.last); elapsed > 0 {
+		w.tokens += elapsed.Seconds() * w.rate
+		if w.tokens > w.burst {
+			w.tokens = w.burst
+		}
+	}
+	w.last = now
+
+	if w.tokens < 1 {
+		return false
+	}
+	w.tokens--
+	return true
+}
+
+func (w *rateLimitWriter) stats() RateLimitStats {
+	return RateLimitStats{
+		Adapter:  w.adapter,
+		Passed:   atomic.LoadUint64(&w.passed),
+		Dropped:  atomic.LoadUint64(&w.dropped),
+		Diverted: atomic.LoadUint64(&w.diverted),
+	}
+}
+
+func (w *rateLimitWriter) Flush() {
+	w.writer.Flush()
+	if w.fallback != nil {
+		w.fallback.Flush()
+	}
+}
+
+func (w *rateLimitWriter) Destroy() {
+	w.writer.Destroy()
+	if w.fallback != nil {
+		w.fallback.Destroy()
+	}
+}
+
+func (w *rateLimitWriter) setRotateHook(fn func(RotateEvent)) {
+	for _, writer := range []logWriter{w.writer, w.fallback} {
+		if rw, ok := writer.(rotateWriter); ok {
+			rw.setRotateHook(fn)
+		}
+	}
+}
+
+func (w *rateLimitWriter) forceRotate() (err error) {
+	for _, writer := range []logWriter{w.writer, w.fallback} {
+		if rw, ok := writer.(rotateWriter); ok {
+			if e := rw.forceRotate(); e != nil && err == nil {
+				err = e
+			}
+		}
+	}
+	return
+}
+
+func (w *rateLimitWriter) setErrorHook(fn func(error)) {
+	for _, writer := range []logWriter{w.writer, w.fallback} {
+		if ew, ok := writer.(errorWriter); ok {
+			ew.setErrorHook(fn)
+		}
+	}
+}
+
+func (w *rateLimitWriter) unwrap() logWriter {
+	return w.writer
+}
+
+// findRateLimit return the rateLimitWriter in wrappers of writer, nil if not found
+func findRateLimit(writer logWriter) *rateLimitWriter {
+	for {
+		if rw, ok := writer.(*rateLimitWriter); ok {
+			return rw
+		}
+		ww, ok := writer.(wrapWriter)
+		if !ok {
+			return nil
+		}
+		writer = ww.unwrap()
+	}
+}
+
+// splitRateLimit remove "ratelimit" option from helper, as adapters reject
+// unknown options. Invalid helper is returned as is for adapter to report
+func splitRateLimit(adapter string, helper string) (rest string, options *rateLimitOptions, err error) {
+	var all map[string]json.RawMessage
+	if json.Unmarshal([]byte(helper), &all) != nil {
+		return helper, nil, nil
+	}
+	raw, ok := all["ratelimit"]
+	if !ok {
+		return helper, nil, nil
+	}
+	delete(all, "ratelimit")
+
+	options = &rateLimitOptions{}
+	if err = decodeHelper(adapter, string(raw), options); err != nil {
+		if ce, ok := err.(*ConfigError); ok {
+			if ce.Field == "" {
+				ce.Field = "ratelimit"
+			} else {
+				ce.Field = "ratelimit." + ce.Field
+			}
+		}
+		return
+	}
+	if options.Rate <= 0 {
+		err = newConfigError(adapter, "ratelimit.rate", "must be positive")
+		return
+	}
+	if options.Burst < 0 {
+		err = newConfigError(adapter, "ratelimit.burst", "must not be negative")
+		return
+	}
+
+	data, _ := json.Marshal(all)
+	return string(data), options, nil
+}
+
+// newRateLimitWriter wrap writer, the fallback adapter is created with the same level
+func newRateLimitWriter(adapter string, level string, writer logWriter, options *rateLimitOptions) (*rateLimitWriter, error) {
+	w := &rateLimitWriter{
+		adapter: adapter,
+		level:   getLevelInt(level),
+		writer:  writer,
+		rate:    options.Rate,
+		burst:   float64(options.Burst),
+		last:    time.Now(),
+	}
+	if w.burst == 0 {
+		w.burst = options.Rate
+	}
+	if w.burst < 1 {
+		w.burst = 1
+	}
+	w.tokens = w.burst
+
+	if options.Adapter != "" {
+		fallback, err := newAdapter(options.Adapter, level, string(options.Helper))
+		if err != nil {
+			if err == NoSupportAdapter {
+				err = newConfigError(adapter, "ratelimit.adapter", "unknown adapter %q", options.Adapter)
+			}
+			return nil, err
+		}
+		w.fallback = fallback
+	}
+	return w, nil
+}
@@ -0,0 +1,86 @@
+package logs
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRateLimitDrop(t *testing.T) {
+	log := NewLogger()
+	if err := log.AddAdapter(AdapterMemory, LevelInfoStr, `{"name":"limited", "ratelimit":{"rate":1, "burst":3}}`); err != nil {
+		t.Fatal(err)
+	}
+	defer log.Close()
+
+	for i := 0; i < 10; i++ {
+		log.Info("info " + strconv.Itoa(i))
+	}
+	log.Debug("below level is not counted")
+
+	records := log.MemoryBuffer("limited").Snapshot()
+	if len(records) != 3 || records[2].Message != "info 2" {
+		t.Error("wrong records:", records)
+	}
+	stats := log.RateLimitStats()
+	if len(stats) != 1 || stats[0].Adapter != AdapterMemory || stats[0].Passed != 3 || stats[0].Dropped != 7 || stats[0].Diverted != 0 {
+		t.Error("wrong stats:", stats)
+	}
+}
+
+func TestRateLimitFallback(t *testing.T) {
+	log := NewLogger()
+	err := log.AddAdapter(AdapterMemory, LevelInfoStr, `{"name":"main", "ratelimit":{"rate":1, "burst":2,
+		"adapter":"memory", "helper":{"name":"fallback"}}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer log.Close()
+
+	for i := 0; i < 5; i++ {
+		log.Info("info " + strconv.Itoa(i))
+	}
+
+	if n := len(log.MemoryBuffer("main").Snapshot()); n != 2 {
+		t.Error("wrong records in main:", n)
+	}
+	stats := log.RateLimitStats()
+	if len(stats) != 1 || stats[0].Passed != 2 || stats[0].Diverted != 3 {
+		t.Error("wrong stats:", stats)
+	}
+	if records := log.recorder[0].(*rateLimitWriter).fallback.(*MemoryBuffer).Snapshot(); len(records) != 3 || records[0].Message != "info 2" {
+		t.Error("wrong records in fallback:", records)
+	}
+}
+
+func TestRateLimitRefill(t *testing.T) {
+	now := time.Now()
+	w := &rateLimitWriter{rate: 10, burst: 2, tokens: 2, last: now}
+
+	if !w.take(now) || !w.take(now) || w.take(now) {
+		t.Fatal("burst is not 2")
+	}
+	if !w.take(now.Add(100*time.Millisecond)) || w.take(now.Add(100*time.Millisecond)) {
+		t.Error("not refilled one token in 100ms")
+	}
+	if !w.take(now.Add(time.Hour)) || !w.take(now.Add(time.Hour)) || w.take(now.Add(time.Hour)) {
+		t.Error("refilled over burst")
+	}
+}
+
+func TestRateLimitInvalid(t *testing.T) {
+	for helper, field := range map[string]string{
+		`{"ratelimit":1}`:                                                      "ratelimit",
+		`{"ratelimit":{"rate":0}}`:                                             "ratelimit.rate",
+		`{"ratelimit":{"rate":1, "burst":-1}}`:                                 "ratelimit.burst",
+		`{"ratelimit":{"rate":1, "unknown":1}}`:                                "ratelimit.unknown",
+		`{"ratelimit":{"rate":1, "adapter":"nothing"}}`:                        "ratelimit.adapter",
+		`{"ratelimit":{"rate":1}, "unknown":1}`:                                "unknown",
+		`{"ratelimit":{"rate":1, "adapter":"memory", "helper":{"records":0}}}`: "records",
+	} {
+		_, err := newAdapter(AdapterMemory, LevelInfoStr, helper)
+		if ce, ok := err.(*ConfigError); !ok || ce.Field != field {
+			t.Errorf("%s: expect error of %q, got %v", helper, field, err)
+		}
+	}
+}
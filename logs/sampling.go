@@ -0,0 +1,162 @@
+package logs
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SuppressedField is added to the first record of a template logged in a new
+// interval, the value is how many records of the template were suppressed
+// in the last interval
+const SuppressedField = "suppressed"
+
+// InvalidSampling is returned by SetSampling for negative values or zero interval
+var InvalidSampling = errors.New("invalid sampling, first and thereafter must not be negative, interval must be positive")
+
+// max templates counted, counters out of interval are removed when exceeded
+const maxSampleCounters = 10000
+
+// Sampling limit records of the same template in every Interval: the First
+// records are logged, then every Thereafter-th record, others are suppressed
+type Sampling struct {
+	First int
+	// Zero means suppress all after First
+	Thereafter int
+	Interval   time.Duration
+}
+
+type sampleKey struct {
+	level    int
+	template string
+}
+
+type sampleCounter struct {
+	start time.Time
+	count int
+	// suppressed in this interval
+	suppressed int
+	// suppressed in last interval and not reported yet
+	report int
+}
+
+type sampler struct {
+	sync.Mutex
+	levels     [LevelError + 1]*Sampling
+	counters   map[sampleKey]*sampleCounter
+	suppressed [LevelError + 1]uint64
+}
+
+// SetSampling sample records at level, keyed by the unformatted message passed
+// to Info, InfoF and so on. Zero Sampling disable sampling of the level.
+// Call it before logging, loggers made by WithFields share it
+func (logger *Logger) SetSampling(level string, sampling Sampling) error {
+	if logger.parent != nil {
+		return logger.parent.SetSampling(level, sampling)
+	}
+
+	l := getLevelInt(level)
+	if l == -1 {
+		return NoSupportLevel
+	}
+	if sampling.First < 0 || sampling.Thereafter < 0 || sampling.Interval < 0 ||
+		(sampling != Sampling{} && sampling.Interval == 0) {
+		return InvalidSampling
+	}
+
+	if logger.sampler == nil {
+		logger.sampler = &sampler{counters: make(map[sampleKey]*sampleCounter)}
+	}
+	s := logger.sampler
+	s.Lock()
+	if sampling == (Sampling{}) {
+		s.levels[l] = nil
+	} else {
+		s.levels[l] = &sampling
+	}
+	s.Unlock()
+	return nil
+}
+
+// Suppressed return count of records suppressed by sampling of every level name
+func (logger *Logger) Suppressed() map[string]uint64 {
+	if logger.parent != nil {
+		return logger.parent.Suppressed()
+	}
+
+	suppressed := make(map[string]uint64)
+	if logger.sampler == nil {
+		return suppressed
+	}
+	for level := range logger.sampler.suppressed {
+		if n := atomic.LoadUint64(&logger.sampler.suppressed[level]); n > 0 {
+			suppressed[levelName[level]] = n
+		}
+	}
+	return suppressed
+}
+
+// sample report whether a record of template should be logged, and how many
+// records of template suppressed in last interval
+func (logger *Logger) sample(level int, template string) (ok bool, report int) {
+	if logger.parent != nil {
+		return logger.parent.sample(level, template)
+	}
+	if logger.sampler == nil {
+		return true, 0
+	}
+	return logger.sampler.allow(level, template, time.Now())
+}
+
+func (s *sampler) allow(level int, template string, now time.Time) (ok bool, report int) {
+	s.Lock()
+	defer s.Unlock()
+
+	sampling := s.levels[level]
+	if sampling == nil {
+		return true, 0
+	}
+
+	key := sampleKey{level: level, template: template}
+	c := s.counters[key]
+	if c == nil {
+		if len(s.counters) >= maxSampleCounters {
+			s.removeExpired(now)
+		}
+		c = &sampleCounter{start: now}
+		s.counters[key] = c
+	} else if now.Sub(c.start) >= sampling.Interval {
+		*c = sampleCounter{start: now, report: c.report + c.suppressed}
+	}
+
+	c.count++
+	if c.count <= sampling.First ||
+		(sampling.Thereafter > 0 && (c.count-sampling.First)%sampling.Thereafter == 0) {
+		report, c.report = c.report, 0
+		return true, report
+	}
+
+	c.suppressed++
+	atomic.AddUint64(&s.suppressed[level], 1)
+	return false, 0
+}
+
+// removeExpired remove counters out of interval, must hold lock
+func (s *sampler) removeExpired(now time.Time) {
+	for key, c := range s.counters {
+		if sampling := s.levels[key.level]; sampling == nil || now.Sub(c.start) >= sampling.Interval {
+			delete(s.counters, key)
+		}
+	}
+}
+
+// withField return a copy of fields with k set
+func withField(fields Fields, k string, v interface{}) Fields {
+	result := make(Fields, len(fields)+1)
+	for key, value := range fields {
+		result[key] = value
+	}
+	result[k] = v
+	return result
+}
@@ -0,0 +1,103 @@
+package logs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSampling(t *testing.T) {
+	log := NewLogger()
+	if err := log.AddAdapter(AdapterMemory, LevelDebugStr, `{"name":"sampling"}`); err != nil {
+		t.Fatal(err)
+	}
+	defer log.Close()
+	if err := log.SetSampling(LevelInfoStr, Sampling{First: 2, Thereafter: 3, Interval: time.Hour}); err != nil {
+		t.Fatal(err)
+	}
+
+	child := log.WithFields(Fields{"a": 1})
+	for i := 0; i < 10; i++ {
+		child.Info("user {} login", i)
+		log.Error("not sampled {}", i)
+	}
+	log.InfoF("other %d", 0)
+
+	var logged []int
+	errors := 0
+	for _, record := range log.MemoryBuffer("sampling").Snapshot() {
+		switch record.Level {
+		case LevelInfoStr:
+			if record.Message != "other 0" {
+				logged = append(logged, int(record.Message[5]-'0'))
+			}
+		case LevelErrorStr:
+			errors++
+		}
+	}
+	// 1st, 2nd, then every 3rd after them
+	if len(logged) != 4 || logged[0] != 0 || logged[1] != 1 || logged[2] != 4 || logged[3] != 7 {
+		t.Error("wrong records logged:", logged)
+	}
+	if errors != 10 {
+		t.Error("error records sampled:", errors)
+	}
+	if suppressed := log.Suppressed(); len(suppressed) != 1 || suppressed[LevelInfoStr] != 6 {
+		t.Error("wrong suppressed:", suppressed)
+	}
+}
+
+func TestSamplingInterval(t *testing.T) {
+	s := &sampler{counters: make(map[sampleKey]*sampleCounter)}
+	s.levels[LevelInfo] = &Sampling{First: 1, Interval: time.Second}
+
+	now := time.Now()
+	if ok, _ := s.allow(LevelInfo, "msg", now); !ok {
+		t.Fatal("first record suppressed")
+	}
+	for i := 0; i < 5; i++ {
+		if ok, _ := s.allow(LevelInfo, "msg", now.Add(time.Millisecond)); ok {
+			t.Fatal("record logged after first")
+		}
+	}
+
+	ok, report := s.allow(LevelInfo, "msg", now.Add(time.Second))
+	if !ok || report != 5 {
+		t.Error("new interval not report suppressed:", ok, report)
+	}
+	if _, report = s.allow(LevelInfo, "msg", now.Add(3*time.Second)); report != 0 {
+		t.Error("suppressed reported twice:", report)
+	}
+}
+
+func TestSamplingSuppressedField(t *testing.T) {
+	log := NewLogger()
+	_ = log.AddAdapter(AdapterMemory, LevelInfoStr, `{"name":"sampling"}`)
+	defer log.Close()
+	_ = log.SetSampling(LevelInfoStr, Sampling{First: 1, Interval: 50 * time.Millisecond})
+
+	log.Info("tick")
+	log.Info("tick")
+	log.Info("tick")
+	time.Sleep(60 * time.Millisecond)
+	log.Info("tick")
+
+	records := log.MemoryBuffer("sampling").Snapshot()
+	if len(records) != 2 || records[1].Fields[SuppressedField] != 2 {
+		t.Error("wrong records:", records)
+	}
+}
+
+func TestSamplingInvalid(t *testing.T) {
+	log := NewLogger()
+	if err := log.SetSampling("verbose", Sampling{First: 1, Interval: time.Second}); err != NoSupportLevel {
+		t.Error("unknown level accepted:", err)
+	}
+	for _, sampling := range []Sampling{{First: -1, Interval: time.Second}, {First: 1}} {
+		if err := log.SetSampling(LevelInfoStr, sampling); err != InvalidSampling {
+			t.Error("invalid sampling accepted:", sampling)
+		}
+	}
+	if err := log.SetSampling(LevelInfoStr, Sampling{}); err != nil {
+		t.Error("disable sampling failed:", err)
+	}
+}
@@ -0,0 +1,306 @@
+package logs
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// SMTPDigest is the data of subject template
+type SMTPDigest struct {
+	// Count of records in the digest, including omitted ones
+	Count int
+	// Highest level name in the digest
+	Level string
+	// Message of the first record
+	First string
+	Host  string
+}
+
+type smtpWriter struct {
+	sync.Mutex
+
+	level int
+
+	// Server address like "smtp.example.com:587", STARTTLS is used if supported
+	Server   string `json:"server"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	// Use tls from connect, like port 465
+	TLS bool `json:"tls"`
+	tlsOptions
+	Timeout Duration `json:"timeout"`
+
+	From string   `json:"from"`
+	To   []string `json:"to"`
+	// text/template of subject, see SMTPDigest
+	Subject string `json:"subject"`
+
+	// Records in the window are sent in one mail
+	Window Duration `json:"window"`
+	// Max records in one mail, others are only counted
+	MaxRecords int `json:"maxrecords"`
+	// Max mails in one hour, records are kept for next mail when exceeded
+	MaxPerHour int `json:"maxperhour"`
+
+	host      string
+	subject   *template.Template
+	tlsConfig *tls.Config
+
+	pending []logMessage
+	omitted int
+	timer   *time.Timer
+	// time of mails sent in the last hour
+	sent   []time.Time
+	closed bool
+
+	// held while sending a mail, so Destroy wait for it
+	sendLock sync.Mutex
+	errorReporter
+}
+
+func (w *smtpWriter) WriteMsg(message logMessage) error {
+	if message.level < w.level {
+		return nil
+	}
+
+	w.Lock()
+	defer w.Unlock()
+	if w.closed {
+		return nil
+	}
+
+	if len(w.pending) < w.MaxRecords {
+		w.pending = append(w.pending, message)
+	} else {
+		w.omitted++
+	}
+	if w.timer == nil {
+		w.timer = time.AfterFunc(time.Duration(w.Window), w.digest)
+	}
+	return nil
+}
+
+// digest send records of the window, called by timer
+func (w *smtpWriter) digest() {
+	w.Lock()
+	if w.closed {
+		w.Unlock()
+		return
+	}
+
+	// keep records until the oldest mail is out of the hour
+	now := time.Now()
+	for len(w.sent) > 0 && now.Sub(w.sent[0]) >= time.Hour {
+		w.sent = w.sent[1:]
+	}
+	if len(w.sent) >= w.MaxPerHour {
+		w.timer = time.AfterFunc(w.sent[0].Add(time.Hour).Sub(now), w.digest)
+		w.Unlock()
+		return
+	}
+
+	w.sent = append(w.sent, now)
+	w.timer = nil
+	messages, omitted := w.take()
+	w.sendLock.Lock()
+	w.Unlock()
+
+	w.send(messages, omitted)
+	w.sendLock.Unlock()
+}
+
+// take return and reset pending records, must hold lock
+func (w *smtpWriter) take() (messages []logMessage, omitted int) {
+	messages, omitted = w.pending, w.omitted
+	w.pending, w.omitted = nil, 0
+	return
+}
+
+func (w *smtpWriter) Flush() {
+}
+
+// Destroy send pending records at once, not limited by MaxPerHour
+func (w *smtpWriter) Destroy() {
+	w.Lock()
+	w.closed = true
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+	}
+	messages, omitted := w.take()
+	w.Unlock()
+
+	w.sendLock.Lock()
+	w.send(messages, omitted)
+	w.sendLock.Unlock()
+}
+
+func (w *smtpWriter) send(messages []logMessage, omitted int) {
+	if len(messages) == 0 {
+		return
+	}
+	if err := w.sendMail(w.mail(messages, omitted)); err != nil {
+		w.reportError(fmt.Errorf("smtp adapter: %d records not sent: %s", len(messages)+omitted, err))
+	}
+}
+
+// mail format records as a plain text mail, caller is always included
+func (w *smtpWriter) mail(messages []logMessage, omitted int) []byte {
+	digest := SMTPDigest{Count: len(messages) + omitted, First: messages[0].message, Host: w.host}
+	level := 0
+	for _, message := range messages {
+		if message.level > level {
+			level = message.level
+		}
+	}
+	digest.Level = levelName[level]
+
+	var subject bytes.Buffer
+	if err := w.subject.Execute(&subject, digest); err != nil {
+		subject.Reset()
+		subject.WriteString(fmt.Sprintf("[%s] %d records from %s", digest.Level, digest.Count, digest.Host))
+	}
+
+	var b bytes.Buffer
+	b.WriteString("From: " + w.From + "\r\n")
+	b.WriteString("To: " + strings.Join(w.To, ", ") + "\r\n")
+	b.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", subject.String()) + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	for _, message := range messages {
+		b.WriteString(strings.Replace(textMessage(LevelTrace, message), "\n", "\r\n", -1))
+	}
+	if omitted > 0 {
+		b.WriteString(fmt.Sprintf("\r\n%d more records omitted\r\n", omitted))
+	}
+	return b.Bytes()
+}
+
+func (w *smtpWriter) sendMail(msg []byte) (err error) {
+	timeout := time.Duration(w.Timeout)
+	var conn net.Conn
+	if w.TLS {
+		conn, err = dialConn("tcp", w.Server, timeout, w.tlsConfig)
+	} else {
+		conn, err = dialConn("tcp", w.Server, timeout, nil)
+	}
+	if err != nil {
+		return
+	}
+	if timeout > 0 {
+		_ = conn.SetDeadline(time.Now().Add(timeout))
+	}
+
+	c, err := smtp.NewClient(conn, w.tlsConfig.ServerName)
+	if err != nil {
+		_ = conn.Close()
+		return
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok && !w.TLS {
+		if err = c.StartTLS(w.tlsConfig); err != nil {
+			return
+		}
+	}
+	if w.Username != "" {
+		if err = c.Auth(smtp.PlainAuth("", w.Username, w.Password, w.tlsConfig.ServerName)); err != nil {
+			return
+		}
+	}
+	if err = c.Mail(w.From); err != nil {
+		return
+	}
+	for _, to := range w.To {
+		if err = c.Rcpt(to); err != nil {
+			return
+		}
+	}
+	data, err := c.Data()
+	if err != nil {
+		return
+	}
+	if _, err = data.Write(msg); err != nil {
+		return
+	}
+	if err = data.Close(); err != nil {
+		return
+	}
+	return c.Quit()
+}
+
+func newSMTPAdapter(level string, helper string) (writer *smtpWriter, err error) {
+	w := getSMTPWriter()
+
+	if err = decodeHelper(AdapterSMTP, helper, w); err != nil {
+		return
+	}
+
+	if w.level = getLevelInt(level); w.level == -1 {
+		err = NoSupportLevel
+		return
+	}
+
+	if err = w.validate(); err != nil {
+		return
+	}
+
+	writer = w
+	return
+}
+
+func (w *smtpWriter) validate() (err error) {
+	host, _, err := net.SplitHostPort(w.Server)
+	if err != nil {
+		return newConfigError(AdapterSMTP, "server", "%s", err)
+	}
+	if w.From == "" {
+		return newConfigError(AdapterSMTP, "from", "must not be empty")
+	}
+	if len(w.To) == 0 {
+		return newConfigError(AdapterSMTP, "to", "must not be empty")
+	}
+	if w.subject, err = template.New("subject").Parse(w.Subject); err != nil {
+		return newConfigError(AdapterSMTP, "subject", "%s", err)
+	}
+	if w.Window <= 0 {
+		return newConfigError(AdapterSMTP, "window", "must be positive")
+	}
+	if w.MaxRecords <= 0 {
+		return newConfigError(AdapterSMTP, "maxrecords", "must be positive")
+	}
+	if w.MaxPerHour <= 0 {
+		return newConfigError(AdapterSMTP, "maxperhour", "must be positive")
+	}
+
+	if w.tlsConfig, err = w.tlsOptions.tlsConfig(AdapterSMTP); err != nil {
+		return
+	}
+	if w.tlsConfig.ServerName == "" {
+		w.tlsConfig.ServerName = host
+	}
+	return nil
+}
+
+func getSMTPWriter() *smtpWriter {
+	host, _ := os.Hostname()
+	return &smtpWriter{
+		Subject:    "[{{.Level}}] {{.Count}} records from {{.Host}}",
+		Timeout:    Duration(10 * time.Second),
+		Window:     Duration(time.Minute),
+		MaxRecords: 100,
+		MaxPerHour: 10,
+		host:       host,
+		level:      LevelError,
+	}
+}
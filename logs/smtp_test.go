@@ -0,0 +1,169 @@
+package logs
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSMTP accept mails with AUTH PLAIN and record their data
+type fakeSMTP struct {
+	sync.Mutex
+	ln    net.Listener
+	mails []string
+	auth  []string
+	rcpts []string
+}
+
+func newFakeSMTP(t *testing.T) *fakeSMTP {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeSMTP{ln: ln}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeSMTP) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) {
+		_, _ = conn.Write([]byte(line + "\r\n"))
+	}
+
+	reply("220 localhost ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+
+		switch cmd {
+		case "EHLO":
+			reply("250-localhost")
+			reply("250 AUTH PLAIN")
+		case "AUTH":
+			s.Lock()
+			s.auth = append(s.auth, line)
+			s.Unlock()
+			reply("235 ok")
+		case "MAIL":
+			reply("250 ok")
+		case "RCPT":
+			s.Lock()
+			s.rcpts = append(s.rcpts, line)
+			s.Unlock()
+			reply("250 ok")
+		case "DATA":
+			reply("354 go ahead")
+			var data []string
+			for {
+				line, err = r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data = append(data, line)
+			}
+			s.Lock()
+			s.mails = append(s.mails, strings.Join(data, ""))
+			s.Unlock()
+			reply("250 ok")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
+
+func (s *fakeSMTP) mailCount() int {
+	s.Lock()
+	defer s.Unlock()
+	return len(s.mails)
+}
+
+func TestSMTPDigest(t *testing.T) {
+	server := newFakeSMTP(t)
+	defer server.ln.Close()
+
+	log := NewLogger()
+	err := log.AddAdapter(AdapterSMTP, LevelWarningStr, `{"server":"`+server.ln.Addr().String()+
+		`", "username":"box", "password":"jan", "from":"log@example.com", "to":["a@example.com","b@example.com"],
+		"subject":"{{.Count}} {{.Level}}: {{.First}}", "window":"50ms", "maxrecords":2, "maxperhour":1}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	log.Warning("disk full")
+	log.Error("write failed")
+	log.Info("ignored")
+	log.Error("omitted")
+	for i := 0; server.mailCount() != 1; i++ {
+		if i > 100 {
+			t.Fatal("digest not sent")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// limited by maxperhour, sent when closed
+	log.Error("last")
+	time.Sleep(100 * time.Millisecond)
+	if server.mailCount() != 1 {
+		t.Error("maxperhour not obeyed")
+	}
+	log.Close()
+
+	if len(server.mails) != 2 || len(server.rcpts) != 4 || len(server.auth) != 2 {
+		t.Fatal("wrong mails:", server.mails, server.rcpts, server.auth)
+	}
+	mail := server.mails[0]
+	if !strings.Contains(mail, "Subject: 3 error: disk full\r\n") {
+		t.Error("wrong subject:", mail)
+	}
+	if !strings.Contains(mail, "smtp_test.go:") || !strings.Contains(mail, "] - write failed\r\n") {
+		t.Error("caller not in mail:", mail)
+	}
+	if !strings.Contains(mail, "1 more records omitted") || strings.Contains(mail, "ignored") {
+		t.Error("wrong records:", mail)
+	}
+	if !strings.Contains(server.mails[1], "] - last\r\n") {
+		t.Error("wrong last mail:", server.mails[1])
+	}
+}
+
+func TestSMTPInvalid(t *testing.T) {
+	log := NewLogger()
+	base := `"server":"localhost:25", "from":"log@example.com", "to":["a@example.com"]`
+	cases := map[string]string{
+		`{"server":"localhost", "from":"a", "to":["b"]}`: "server",
+		`{"server":"localhost:25", "to":["b"]}`:          "from",
+		`{"server":"localhost:25", "from":"a"}`:          "to",
+		`{` + base + `, "subject":"{{.Count"}`:           "subject",
+		`{` + base + `, "window":"0s"}`:                  "window",
+		`{` + base + `, "maxperhour":0}`:                 "maxperhour",
+	}
+	for helper, field := range cases {
+		err := log.AddAdapter(AdapterSMTP, LevelErrorStr, helper)
+		if cErr, ok := err.(*ConfigError); !ok || cErr.Field != field {
+			t.Error(helper, "not get ConfigError on", field, "but", err)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package logs
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+)
+
+var SpoolFull = errors.New("spool is full, record dropped")
+
+// spool keep records in a file while remote is unavailable, one record a line.
+// It is not safe for concurrent use
+type spool struct {
+	name    string
+	maxSize ByteSize
+	file    *fileWriter
+	// bytes at the head of file already replayed, saved in a sidecar file,
+	// so a failed replay need not rewrite the file
+	offset int64
+}
+
+func newSpool(name string, maxSize ByteSize) (s *spool, err error) {
+	s = &spool{name: name, maxSize: maxSize}
+
+	// reuse file adapter to write spool
+	helper, _ := json.Marshal(map[string]interface{}{"filename": name, "rotate": false})
+	if s.file, err = newFileAdapter(LevelTraceStr, string(helper)); err != nil {
+		return nil, err
+	}
+	// offset left by last run
+	if data, err := ioutil.ReadFile(s.offsetName()); err == nil {
+		s.offset, _ = strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	}
+	if s.offset < 0 || s.offset > int64(s.file.maxSizeCurSize) {
+		s.offset = 0
+	}
+	return
+}
+
+func (s *spool) offsetName() string {
+	return sidecarName(s.name, ".offset")
+}
+
+// pending report whether spool have records not replayed
+func (s *spool) pending() bool {
+	return int64(s.file.maxSizeCurSize) > s.offset
+}
+
+// add append one record, record must not contain newline
+func (s *spool) add(record string) error {
+	if s.maxSize > 0 && ByteSize(int64(s.file.maxSizeCurSize)-s.offset) >= s.maxSize {
+		return SpoolFull
+	}
+	return s.file.writeString(record + "\n")
+}
+
+// replay call send with at most batch records each time in order, stop at
+// the first error, records not sent are kept in spool
+func (s *spool) replay(batch int, send func(records []string) error) (err error) {
+	if !s.pending() {
+		return nil
+	}
+
+	fd, err := os.Open(s.name)
+	if err != nil {
+		return
+	}
+	if _, err = fd.Seek(s.offset, io.SeekStart); err != nil {
+		_ = fd.Close()
+		return
+	}
+
+	var (
+		size    int64
+		records []string
+	)
+	r := bufio.NewReader(fd)
+	for {
+		line, readErr := r.ReadString('\n')
+		// EOF or a broken line at the end
+		if readErr == nil {
+			records = append(records, line[:len(line)-1])
+			size += int64(len(line))
+		}
+		if len(records) > 0 && (len(records) >= batch || readErr != nil) {
+			if err = send(records); err != nil {
+				break
+			}
+			s.offset += size
+			records, size = nil, 0
+		}
+		if readErr != nil {
+			break
+		}
+	}
+	_ = fd.Close()
+
+	if err != nil {
+		return s.keep(err)
+	}
+
+	s.file.Destroy()
+	_ = os.Remove(s.name)
+	_ = os.Remove(s.offsetName())
+	s.offset = 0
+	return s.file.startLog()
+}
+
+// keep save the offset of records not sent, cut the sent records from spool
+// file only if they are more than half of it, so it is not rewritten by every
+// failed replay
+func (s *spool) keep(sendErr error) (err error) {
+	if s.offset*2 <= int64(s.file.maxSizeCurSize) {
+		err = s.saveOffset()
+	} else {
+		err = s.cut()
+	}
+	if err != nil {
+		return err
+	}
+	return sendErr
+}
+
+func (s *spool) saveOffset() error {
+	return ioutil.WriteFile(s.offsetName(), []byte(strconv.FormatInt(s.offset, 10)), s.file.perm)
+}
+
+// cut remove the sent records from spool file
+func (s *spool) cut() error {
+	fd, err := os.Open(s.name)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+
+	tmpName := s.name + ".tmp"
+	tmp, err := os.OpenFile(tmpName, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, s.file.perm)
+	if err == nil {
+		if _, err = fd.Seek(s.offset, io.SeekStart); err == nil {
+			_, err = io.Copy(tmp, fd)
+		}
+		if cErr := tmp.Close(); err == nil {
+			err = cErr
+		}
+	}
+	if err != nil {
+		_ = os.Remove(tmpName)
+		// records will be sent again from offset
+		return s.saveOffset()
+	}
+
+	// crash after it only send records again, never lose them
+	_ = os.Remove(s.offsetName())
+	s.file.Destroy()
+	if err = os.Rename(tmpName, s.name); err != nil {
+		_ = os.Remove(tmpName)
+		_ = s.saveOffset()
+		_ = s.file.startLog()
+		return err
+	}
+	s.offset = 0
+	return s.file.startLog()
+}
+
+func (s *spool) flush() {
+	s.file.Flush()
+}
+
+func (s *spool) destroy() {
+	s.file.Destroy()
+}
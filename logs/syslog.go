@@ -0,0 +1,371 @@
+package logs
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	SyslogRFC5424 = "rfc5424"
+	SyslogRFC3164 = "rfc3164"
+)
+
+// syslog severity of each level, trace and debug are both debug
+var syslogSeverity = []int{7, 7, 6, 4, 3}
+
+var syslogFacility = map[string]int{
+	"kern":     0,
+	"user":     1,
+	"mail":     2,
+	"daemon":   3,
+	"auth":     4,
+	"syslog":   5,
+	"lpr":      6,
+	"news":     7,
+	"uucp":     8,
+	"cron":     9,
+	"authpriv": 10,
+	"ftp":      11,
+	"local0":   16,
+	"local1":   17,
+	"local2":   18,
+	"local3":   19,
+	"local4":   20,
+	"local5":   21,
+	"local6":   22,
+	"local7":   23,
+}
+
+// local syslog sockets to try when address not set
+var syslogLocalAddress = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+type syslogWriter struct {
+	sync.Mutex
+
+	level int
+	conn  net.Conn
+	// network of conn, may differ from Network when using local socket
+	connNetwork string
+	// closed when peer close the stream conn
+	connClosed chan struct{}
+
+	// "unixgram", "unix", "udp", "tcp" or "tls", empty means local syslog socket
+	Network string `json:"network"`
+	Address string `json:"address"`
+	// rfc5424 or rfc3164
+	Format string `json:"format"`
+	// "octet" counting or "newline", only for stream network
+	Framing  string `json:"framing"`
+	Facility string `json:"facility"`
+	AppName  string `json:"appname"`
+	Hostname string `json:"hostname"`
+	// SD-ID of the structured-data element carrying record fields
+	SDID    string   `json:"sdid"`
+	Timeout Duration `json:"timeout"`
+
+	// Reconnect delay, double after each failure until MaxBackoff
+	Backoff    Duration `json:"backoff"`
+	MaxBackoff Duration `json:"maxbackoff"`
+	retry      backoff
+
+	tlsOptions
+
+	facility  int
+	pid       int
+	tlsConfig *tls.Config
+}
+
+func (w *syslogWriter) WriteMsg(message logMessage) (err error) {
+	if message.level < w.level {
+		return nil
+	}
+
+	w.Lock()
+	defer w.Unlock()
+
+	data := w.frame(w.formatMessage(message))
+
+	if w.conn != nil && isClosed(w.connClosed) {
+		_ = w.conn.Close()
+		w.conn = nil
+	}
+
+	// write once, if failed reconnect and write again
+	if w.conn != nil {
+		if err = w.write(data); err == nil {
+			return
+		}
+		_ = w.conn.Close()
+		w.conn = nil
+	}
+
+	if err = w.connect(); err != nil {
+		return
+	}
+	if err = w.write(data); err != nil {
+		_ = w.conn.Close()
+		w.conn = nil
+		w.retry.fail()
+	}
+	return
+}
+
+func (w *syslogWriter) write(data []byte) error {
+	if w.Timeout > 0 {
+		_ = w.conn.SetWriteDeadline(time.Now().Add(time.Duration(w.Timeout)))
+	}
+	_, err := w.conn.Write(data)
+	return err
+}
+
+func (w *syslogWriter) Flush() {
+}
+
+func (w *syslogWriter) Destroy() {
+	w.Lock()
+	if w.conn != nil {
+		_ = w.conn.Close()
+		w.conn = nil
+	}
+	w.Unlock()
+}
+
+// connect dial to syslog, and watch stream conn for peer close, as a write
+// to closed tcp conn may success and the message will be lost.
+// Fail fast while the backoff of last failure not expired
+func (w *syslogWriter) connect() (err error) {
+	if !w.retry.ready() {
+		return fmt.Errorf("syslog adapter: syslog is down, retry after %s", w.retry.next.Format(time.RFC3339))
+	}
+	if err = w.dial(); err != nil {
+		w.conn = nil
+		w.retry.fail()
+		return
+	}
+	w.retry.reset()
+
+	w.connClosed = nil
+	if isStreamNetwork(w.connNetwork) {
+		// syslog server never send anything
+		w.connClosed = watchPeerClose(w.conn)
+	}
+	return
+}
+
+func (w *syslogWriter) dial() (err error) {
+	timeout := time.Duration(w.Timeout)
+
+	if w.Network == "" {
+		addresses := syslogLocalAddress
+		if w.Address != "" {
+			addresses = []string{w.Address}
+		}
+		for _, address := range addresses {
+			for _, network := range []string{"unixgram", "unix"} {
+				if w.conn, err = net.DialTimeout(network, address, timeout); err == nil {
+					w.connNetwork = network
+					return
+				}
+			}
+		}
+		return fmt.Errorf("syslog adapter: no local syslog socket: %s", err)
+	}
+
+	w.connNetwork = w.Network
+	if w.Network == "tls" {
+		w.conn, err = dialConn("tcp", w.Address, timeout, w.tlsConfig)
+		return
+	}
+	w.conn, err = dialConn(w.Network, w.Address, timeout, nil)
+	return
+}
+
+func (w *syslogWriter) frame(msg string) []byte {
+	if !isStreamNetwork(w.connNetwork) {
+		return []byte(msg)
+	}
+	// local syslog daemon only know newline
+	if w.Framing == "octet" && w.Network != "" {
+		return []byte(strconv.Itoa(len(msg)) + " " + msg)
+	}
+	return []byte(msg + "\n")
+}
+
+func (w *syslogWriter) formatMessage(message logMessage) string {
+	pri := w.facility*8 + syslogSeverity[message.level]
+
+	msg := message.message
+	if w.level == LevelTrace {
+		msg = fmt.Sprintf("[%s] [%s:%d] %s", message.trace.funcName, message.trace.file, message.trace.line, msg)
+	}
+
+	if w.Format == SyslogRFC3164 {
+		if len(message.fields) > 0 {
+			msg += " " + fieldsString(message.fields)
+		}
+		var host string
+		if w.Network != "" {
+			// local syslog daemon add hostname itself
+			host = w.Hostname + " "
+		}
+		return fmt.Sprintf("<%d>%s %s%s[%d]: %s", pri, message.time.Format(time.Stamp),
+			host, w.AppName, w.pid, msg)
+	}
+
+	return fmt.Sprintf("<%d>1 %s %s %s %d - %s %s", pri,
+		message.time.Format("2006-01-02T15:04:05.000000Z07:00"),
+		syslogHeaderField(w.Hostname, 255), syslogHeaderField(w.AppName, 48), w.pid,
+		w.structuredData(message.fields), msg)
+}
+
+// structuredData format fields as one RFC 5424 SD-ELEMENT
+func (w *syslogWriter) structuredData(fields Fields) string {
+	if len(fields) == 0 {
+		return "-"
+	}
+
+	var b strings.Builder
+	b.WriteString("[" + w.SDID)
+	for _, k := range sortedFieldKeys(fields) {
+		name := syslogSDName(k)
+		if name == "" {
+			continue
+		}
+		b.WriteString(" " + name + `="`)
+		b.WriteString(syslogSDValueReplacer.Replace(fmt.Sprintf("%+v", fields[k])))
+		b.WriteString(`"`)
+	}
+	b.WriteString("]")
+	return b.String()
+}
+
+var syslogSDValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+// syslogSDName keep only chars allowed by SD-NAME
+func syslogSDName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r <= 32 || r >= 127 || r == '=' || r == ']' || r == '"' {
+			return -1
+		}
+		return r
+	}, name)
+	if len(name) > 32 {
+		name = name[:32]
+	}
+	return name
+}
+
+// syslogHeaderField make value usable in RFC 5424 header, "-" means nil
+func syslogHeaderField(value string, maxLen int) string {
+	value = strings.Map(func(r rune) rune {
+		if r <= 32 || r >= 127 {
+			return -1
+		}
+		return r
+	}, value)
+	if value == "" {
+		return "-"
+	}
+	if len(value) > maxLen {
+		value = value[:maxLen]
+	}
+	return value
+}
+
+func newSyslogAdapter(level string, helper string) (writer *syslogWriter, err error) {
+	w := getSyslogWriter()
+
+	if err = decodeHelper(AdapterSyslog, helper, w); err != nil {
+		return
+	}
+
+	if w.level = getLevelInt(level); w.level == -1 {
+		err = NoSupportLevel
+		return
+	}
+
+	if err = w.validate(); err != nil {
+		return
+	}
+
+	w.retry = backoff{initial: time.Duration(w.Backoff), max: time.Duration(w.MaxBackoff)}
+	// syslog can be down at start, records fail until it is up
+	_ = w.connect()
+
+	writer = w
+	return
+}
+
+func (w *syslogWriter) validate() (err error) {
+	switch w.Network {
+	case "":
+	case "unixgram", "unix", "udp", "tcp", "tls":
+		if w.Address == "" {
+			return newConfigError(AdapterSyslog, "address", "must be set for network %s", w.Network)
+		}
+	default:
+		return newConfigError(AdapterSyslog, "network", "unknown network %q", w.Network)
+	}
+
+	switch w.Format {
+	case SyslogRFC5424, SyslogRFC3164:
+	default:
+		return newConfigError(AdapterSyslog, "format", "unknown format %q", w.Format)
+	}
+
+	switch w.Framing {
+	case "":
+		w.Framing = "newline"
+		if w.Format == SyslogRFC5424 {
+			// RFC 6587 octet counting
+			w.Framing = "octet"
+		}
+	case "octet", "newline":
+	default:
+		return newConfigError(AdapterSyslog, "framing", "unknown framing %q", w.Framing)
+	}
+
+	var ok bool
+	if w.facility, ok = syslogFacility[strings.ToLower(w.Facility)]; !ok {
+		return newConfigError(AdapterSyslog, "facility", "unknown facility %q", w.Facility)
+	}
+
+	if syslogSDName(w.SDID) != w.SDID || w.SDID == "" {
+		return newConfigError(AdapterSyslog, "sdid", "%q is not a valid SD-ID", w.SDID)
+	}
+
+	if w.Backoff <= 0 {
+		return newConfigError(AdapterSyslog, "backoff", "must be positive")
+	}
+	if w.MaxBackoff < w.Backoff {
+		return newConfigError(AdapterSyslog, "maxbackoff", "must not be less than backoff")
+	}
+
+	if w.Network == "tls" {
+		w.tlsConfig, err = w.tlsOptions.tlsConfig(AdapterSyslog)
+	}
+	return
+}
+
+func getSyslogWriter() *syslogWriter {
+	hostname, _ := os.Hostname()
+	return &syslogWriter{
+		Format:     SyslogRFC5424,
+		Facility:   "user",
+		AppName:    filepath.Base(os.Args[0]),
+		Hostname:   hostname,
+		SDID:       "fields@32473",
+		Timeout:    Duration(5 * time.Second),
+		Backoff:    Duration(time.Second),
+		MaxBackoff: Duration(time.Minute),
+		pid:        os.Getpid(),
+		level:      LevelInfo,
+	}
+}
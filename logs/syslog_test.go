@@ -0,0 +1,198 @@
+package logs
+
+import (
+	"bufio"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSyslogUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	log := NewLogger()
+	err = log.AddAdapter(AdapterSyslog, LevelDebugStr, `{"network":"udp", "address":"`+conn.LocalAddr().String()+
+		`", "facility":"local0", "appname":"app", "hostname":"host"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	log.WithFields(Fields{"request": "a\"b]", "user": 1}).Error("error")
+	log.Debug("debug")
+	log.Close()
+
+	buf := make([]byte, 2048)
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := string(buf[:n])
+	// local0 * 8 + error
+	if !strings.HasPrefix(msg, "<131>1 ") {
+		t.Error("wrong header:", msg)
+	}
+	if !strings.Contains(msg, " host app "+strconv.Itoa(os.Getpid())+" - ") {
+		t.Error("wrong hostname or appname:", msg)
+	}
+	if !strings.HasSuffix(msg, ` [fields@32473 request="a\"b\]" user="1"] error`) {
+		t.Error("wrong structured data:", msg)
+	}
+
+	n, _, err = conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg = string(buf[:n]); !strings.HasPrefix(msg, "<135>1 ") || !strings.HasSuffix(msg, " - debug") {
+		t.Error("wrong debug message:", msg)
+	}
+}
+
+func TestSyslogTCPReconnect(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	received := make(chan string, 8)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			r := bufio.NewReader(conn)
+			// octet counting framing
+			size, err := r.ReadString(' ')
+			if err != nil {
+				_ = conn.Close()
+				continue
+			}
+			n, _ := strconv.Atoi(strings.TrimSpace(size))
+			msg := make([]byte, n)
+			_, _ = r.Read(msg)
+			received <- string(msg)
+			// drop the connection after every message
+			_ = conn.Close()
+		}
+	}()
+
+	log := NewLogger()
+	err = log.AddAdapter(AdapterSyslog, LevelInfoStr, `{"network":"tcp", "address":"`+ln.Addr().String()+`"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		log.Info("message {}", i)
+		select {
+		case msg := <-received:
+			if !strings.HasSuffix(msg, "message "+strconv.Itoa(i)) {
+				t.Error("wrong message:", msg)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("message", i, "not received")
+		}
+		// let the writer notice the closed connection
+		time.Sleep(10 * time.Millisecond)
+	}
+	log.Close()
+}
+
+func TestSyslogLocalRFC3164(t *testing.T) {
+	dir, err := ioutil.TempDir("", "syslog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	address := filepath.Join(dir, "log")
+	conn, err := net.ListenPacket("unixgram", address)
+	if err != nil {
+		t.Skip("unixgram not supported:", err)
+	}
+	defer conn.Close()
+
+	log := NewLogger()
+	err = log.AddAdapter(AdapterSyslog, LevelInfoStr, `{"address":"`+address+
+		`", "format":"rfc3164", "facility":"daemon", "appname":"app"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log.WithFields(Fields{"k": "v"}).Warning("warning")
+	log.Close()
+
+	buf := make([]byte, 2048)
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := string(buf[:n])
+	if !strings.HasPrefix(msg, "<28>") || !strings.HasSuffix(msg, " app["+strconv.Itoa(os.Getpid())+"]: warning k=v") {
+		t.Error("wrong message:", msg)
+	}
+}
+
+func TestSyslogBackoff(t *testing.T) {
+	// a closed port, nothing listen on it
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := ln.Addr().String()
+	_ = ln.Close()
+
+	w, err := newSyslogAdapter(LevelInfoStr, `{"network":"tcp", "address":"`+address+`", "backoff":"1h", "maxbackoff":"1h"}`)
+	if err != nil {
+		t.Fatal("syslog down at start should not fail:", err)
+	}
+	defer w.Destroy()
+
+	message := logMessage{level: LevelInfo, message: "down", time: time.Now()}
+	if err = w.WriteMsg(message); err == nil || !strings.Contains(err.Error(), "retry after") {
+		t.Error("not fail fast in backoff:", err)
+	}
+
+	// up again, but still in backoff
+	ln, err = net.Listen("tcp", address)
+	if err != nil {
+		t.Skip("can not listen again:", err)
+	}
+	defer ln.Close()
+	if err = w.WriteMsg(message); err == nil {
+		t.Error("dial in backoff")
+	}
+	w.retry.reset()
+	if err = w.WriteMsg(message); err != nil {
+		t.Error("not reconnect after backoff:", err)
+	}
+}
+
+func TestSyslogInvalid(t *testing.T) {
+	log := NewLogger()
+	cases := map[string]string{
+		`{"network":"carrier-pigeon", "address":"x"}`:        "network",
+		`{"network":"udp"}`:                                  "address",
+		`{"network":"udp", "address":"x", "format":"json"}`:  "format",
+		`{"network":"udp", "address":"x", "facility":"foo"}`: "facility",
+		`{"network":"udp", "address":"x", "sdid":"a b"}`:     "sdid",
+		`{"network":"udp", "address":"x", "backoff":"0s"}`:   "backoff",
+	}
+	for helper, field := range cases {
+		err := log.AddAdapter(AdapterSyslog, LevelInfoStr, helper)
+		if cErr, ok := err.(*ConfigError); !ok || cErr.Field != field {
+			t.Error(helper, "not get ConfigError on", field, "but", err)
+		}
+	}
+}
@@ -0,0 +1,301 @@
+package logs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/template"
+	"time"
+)
+
+// WebhookMessage is the data of webhook body template
+type WebhookMessage struct {
+	Time    time.Time
+	Level   string
+	Message string
+	File    string
+	Line    int
+	Func    string
+	Fields  Fields
+	Host    string
+	// Times the message repeated in the dedup window after the first one,
+	// zero for the first one
+	Repeated int
+	// Text is the formatted record, with "(repeated N times)" if repeated
+	Text string
+}
+
+var webhookFuncs = template.FuncMap{
+	// json encode v, to put string into json body safely
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// webhookDedup count the same message in dedup window
+type webhookDedup struct {
+	message logMessage
+	count   int
+	timer   *time.Timer
+}
+
+type webhookWriter struct {
+	// first field to make sure 64-bit aligned
+	counter deliveryCounter
+
+	sync.Mutex
+
+	level int
+
+	// Incoming webhook url of Slack, Mattermost and so on
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers"`
+	Timeout Duration          `json:"timeout"`
+	tlsOptions
+
+	// text/template of json body, see WebhookMessage
+	Template string `json:"template"`
+	// The same message in the window is sent once, then with repeated times when window end
+	DedupWindow Duration `json:"dedupwindow"`
+	// Max posts in one minute, more messages are dropped
+	MaxPerMinute int `json:"maxperminute"`
+
+	// Retry on 429, 5xx and network error
+	batchOptions
+
+	host     string
+	template *template.Template
+	client   *http.Client
+	batcher  *batcher
+	dedup    map[string]*webhookDedup
+	closed   bool
+	errorReporter
+
+	// time of posts in the last minute, only used in batcher goroutine
+	posted []time.Time
+}
+
+func (w *webhookWriter) WriteMsg(message logMessage) error {
+	if message.level < w.level {
+		return nil
+	}
+
+	w.Lock()
+	defer w.Unlock()
+	if w.closed {
+		return nil
+	}
+
+	if w.DedupWindow > 0 {
+		key := levelName[message.level] + "\x00" + message.message
+		if d, ok := w.dedup[key]; ok {
+			d.count++
+			return nil
+		}
+		d := &webhookDedup{message: message}
+		d.timer = time.AfterFunc(time.Duration(w.DedupWindow), func() {
+			w.Lock()
+			defer w.Unlock()
+			if w.dedup[key] == d {
+				delete(w.dedup, key)
+				w.summary(d)
+			}
+		})
+		w.dedup[key] = d
+	}
+	return w.add(message, 0)
+}
+
+// summary queue the repeated times of dedup, must hold lock
+func (w *webhookWriter) summary(d *webhookDedup) {
+	if d.count > 0 {
+		_ = w.add(d.message, d.count)
+	}
+}
+
+// add render message and queue it, must hold lock
+func (w *webhookWriter) add(message logMessage, repeated int) error {
+	var body bytes.Buffer
+	if err := w.template.Execute(&body, w.data(message, repeated)); err != nil {
+		atomic.AddUint64(&w.counter.failed, 1)
+		return err
+	}
+
+	err := w.batcher.add(batchRecord{message: message, data: body.Bytes()})
+	if err != nil {
+		atomic.AddUint64(&w.counter.dropped, 1)
+	}
+	return err
+}
+
+func (w *webhookWriter) data(message logMessage, repeated int) WebhookMessage {
+	text := strings.TrimSuffix(textMessage(w.level, message), "\n")
+	if repeated > 0 {
+		text += fmt.Sprintf(" (repeated %d times)", repeated)
+	}
+	return WebhookMessage{
+		Time:     message.time,
+		Level:    levelName[message.level],
+		Message:  message.message,
+		File:     message.trace.file,
+		Line:     message.trace.line,
+		Func:     message.trace.funcName,
+		Fields:   message.fields,
+		Host:     w.host,
+		Repeated: repeated,
+		Text:     text,
+	}
+}
+
+func (w *webhookWriter) Flush() {
+	w.batcher.flush()
+}
+
+// Destroy send repeated times of messages in dedup window, then stop
+func (w *webhookWriter) Destroy() {
+	w.Lock()
+	if !w.closed {
+		w.closed = true
+		for key, d := range w.dedup {
+			d.timer.Stop()
+			delete(w.dedup, key)
+			w.summary(d)
+		}
+	}
+	w.Unlock()
+	w.batcher.close()
+}
+
+func (w *webhookWriter) reportError(err error) {
+	w.counter.setError(err)
+	w.errorReporter.reportError(err)
+}
+
+func (w *webhookWriter) deliveryStats() DeliveryStats {
+	return w.counter.stats(AdapterWebhook)
+}
+
+// send is called by batcher in its goroutine, every record is one post
+func (w *webhookWriter) send(batch []batchRecord) {
+	for _, record := range batch {
+		if !w.allow() {
+			atomic.AddUint64(&w.counter.dropped, 1)
+			continue
+		}
+
+		err := w.retry(&w.counter, func() (bool, error) {
+			return w.post(record.data)
+		})
+		if err != nil {
+			atomic.AddUint64(&w.counter.failed, 1)
+			w.reportError(err)
+			continue
+		}
+		atomic.AddUint64(&w.counter.sent, 1)
+		atomic.AddUint64(&w.counter.batches, 1)
+	}
+}
+
+// allow report whether a post can be made under MaxPerMinute
+func (w *webhookWriter) allow() bool {
+	now := time.Now()
+	for len(w.posted) > 0 && now.Sub(w.posted[0]) >= time.Minute {
+		w.posted = w.posted[1:]
+	}
+	if len(w.posted) >= w.MaxPerMinute {
+		return false
+	}
+	w.posted = append(w.posted, now)
+	return true
+}
+
+func (w *webhookWriter) post(body []byte) (retryable bool, err error) {
+	req, err := http.NewRequest(http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	setHTTPHeaders(req, w.Headers)
+
+	_, retryable, err = doHTTP(w.client, req)
+	return
+}
+
+func newWebhookAdapter(level string, helper string) (writer *webhookWriter, err error) {
+	w := getWebhookWriter()
+
+	if err = decodeHelper(AdapterWebhook, helper, w); err != nil {
+		return
+	}
+
+	if w.level = getLevelInt(level); w.level == -1 {
+		err = NoSupportLevel
+		return
+	}
+
+	if err = w.validate(); err != nil {
+		return
+	}
+
+	tlsConfig, err := w.tlsOptions.tlsConfig(AdapterWebhook)
+	if err != nil {
+		return
+	}
+	w.client = newHTTPClient(tlsConfig, time.Duration(w.Timeout))
+	w.dedup = make(map[string]*webhookDedup)
+	w.batcher = w.newBatcher(w.send)
+
+	writer = w
+	return
+}
+
+func (w *webhookWriter) validate() (err error) {
+	u, err := url.Parse(w.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return newConfigError(AdapterWebhook, "url", "%q is not a http or https url", w.URL)
+	}
+
+	if w.template, err = template.New("body").Funcs(webhookFuncs).Parse(w.Template); err != nil {
+		return newConfigError(AdapterWebhook, "template", "%s", err)
+	}
+	// check the body is json with a sample message
+	var body bytes.Buffer
+	sample := logMessage{time: time.Now(), level: LevelError, message: `sample "message"`, fields: Fields{"key": "value"}}
+	if err = w.template.Execute(&body, w.data(sample, 1)); err != nil {
+		return newConfigError(AdapterWebhook, "template", "%s", err)
+	}
+	if !json.Valid(body.Bytes()) {
+		return newConfigError(AdapterWebhook, "template", "body is not json: %s", body.String())
+	}
+
+	if w.DedupWindow < 0 {
+		return newConfigError(AdapterWebhook, "dedupwindow", "must not be negative")
+	}
+	if w.MaxPerMinute <= 0 {
+		return newConfigError(AdapterWebhook, "maxperminute", "must be positive")
+	}
+	return w.batchOptions.validate(AdapterWebhook)
+}
+
+func getWebhookWriter() *webhookWriter {
+	host, _ := os.Hostname()
+	options := defaultBatchOptions()
+	// post at once, batch is only the queue
+	options.BatchSize = 1
+	return &webhookWriter{
+		Timeout:      Duration(10 * time.Second),
+		Template:     `{"text": {{json .Text}}}`,
+		DedupWindow:  Duration(time.Minute),
+		MaxPerMinute: 20,
+		batchOptions: options,
+		host:         host,
+		level:        LevelError,
+	}
+}
@@ -0,0 +1,114 @@
+package logs
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// webhookServer record text of posts, response status in order, empty means 200
+type webhookServer struct {
+	sync.Mutex
+	texts  []string
+	status []int
+}
+
+func (s *webhookServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+	if len(s.status) > 0 {
+		w.WriteHeader(s.status[0])
+		s.status = s.status[1:]
+		return
+	}
+
+	var body struct {
+		Text string `json:"text"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	s.texts = append(s.texts, body.Text)
+}
+
+func TestWebhookDedup(t *testing.T) {
+	server := &webhookServer{status: []int{http.StatusServiceUnavailable}}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	log := NewLogger()
+	err := log.AddAdapter(AdapterWebhook, LevelErrorStr, `{"url":"`+ts.URL+
+		`", "dedupwindow":"50ms", "backoff":"1ms"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 38; i++ {
+		log.Error("connect \"db\" failed")
+	}
+	log.Warning("ignored")
+	time.Sleep(100 * time.Millisecond)
+	log.Error("connect \"db\" failed")
+	log.Close()
+
+	texts := server.texts
+	if len(texts) != 3 {
+		t.Fatal("wrong posts:", texts)
+	}
+	if !strings.HasSuffix(texts[0], ` - connect "db" failed`) ||
+		!strings.HasSuffix(texts[1], ` - connect "db" failed (repeated 37 times)`) ||
+		!strings.HasSuffix(texts[2], ` - connect "db" failed`) {
+		t.Error("wrong texts:", texts)
+	}
+	if stats := log.DeliveryStats(); stats[0].Sent != 3 || stats[0].Retries != 1 {
+		t.Error("wrong stats:", stats)
+	}
+}
+
+func TestWebhookTemplateAndLimit(t *testing.T) {
+	server := &webhookServer{}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	log := NewLogger()
+	err := log.AddAdapter(AdapterWebhook, LevelErrorStr, `{"url":"`+ts.URL+`", "maxperminute":2,
+		"template":"{\"text\": {{json .Message}}, \"username\": \"{{.Level}}\"}"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log.Error("first")
+	log.Error("second")
+	log.Error("third")
+	log.Close()
+
+	if texts := server.texts; strings.Join(texts, ",") != "first,second" {
+		t.Error("wrong posts:", texts)
+	}
+	if stats := log.DeliveryStats(); stats[0].Sent != 2 || stats[0].Dropped != 1 {
+		t.Error("wrong stats:", stats)
+	}
+}
+
+func TestWebhookInvalid(t *testing.T) {
+	log := NewLogger()
+	cases := map[string]string{
+		`{"url":"hooks.slack.com"}`:                                      "url",
+		`{"url":"http://localhost", "template":"{{.Text"}`:               "template",
+		`{"url":"http://localhost", "template":"{\"text\": {{.Text}}}"}`: "template",
+		`{"url":"http://localhost", "template":"{{.Unknown}}"}`:          "template",
+		`{"url":"http://localhost", "dedupwindow":"-1s"}`:                "dedupwindow",
+		`{"url":"http://localhost", "maxperminute":0}`:                   "maxperminute",
+		`{"url":"http://localhost", "retries":-1}`:                       "retries",
+	}
+	for helper, field := range cases {
+		err := log.AddAdapter(AdapterWebhook, LevelErrorStr, helper)
+		if cErr, ok := err.(*ConfigError); !ok || cErr.Field != field {
+			t.Error(helper, "not get ConfigError on", field, "but", err)
+		}
+	}
+}